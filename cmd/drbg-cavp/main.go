@@ -0,0 +1,39 @@
+// drbg-cavp运行CAVP Hash_DRBG.rsp或ACVP JSON格式的Hash_DRBG测试向量,不指定文件时运行内置的SM3回归测试向量
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: drbg-cavp [向量文件(.rsp/.json)...]")
+	}
+	flag.Parse()
+
+	pass := true
+	if flag.NArg() == 0 {
+		report := drbg.Test_SM3_Vectors()
+		fmt.Print("内置SM3回归测试向量: ", report)
+		pass = len(report.Failures) == 0
+	}
+	for _, path := range flag.Args() {
+		report, err := drbg.Run_Vector_File(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, path, "运行失败:", err)
+			pass = false
+			continue
+		}
+		fmt.Print(path, ": ", report)
+		if len(report.Failures) != 0 {
+			pass = false
+		}
+	}
+	if !pass {
+		os.Exit(1)
+	}
+}
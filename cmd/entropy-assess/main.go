@@ -0,0 +1,125 @@
+// entropy-assess对entropy-collect采集的样本文件运行SP 800-90B估计方法,为每个熵源输出JSON/Markdown报告
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jellygdh/drbg_sm3/estimate"
+)
+
+// 单个熵源的评估报告
+type Report struct {
+	Source string                 //熵源名称
+	File   string                 //样本文件
+	NonIID estimate.NonIID_Report //非IID估计结果
+	IID    *estimate.IID_Result   `json:",omitempty"` //IID检验结果
+}
+
+// 报告->Markdown
+func (report *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", report.Source)
+	fmt.Fprintf(&b, "- 样本文件: `%s`\n", report.File)
+	fmt.Fprintf(&b, "- 符号个数: %d\n", report.NonIID.Samples)
+	fmt.Fprintf(&b, "- 每个符号的比特数: %d\n", report.NonIID.Bits_Per_Symbol)
+	fmt.Fprintf(&b, "- 最小熵: **%.6f** 比特/符号\n\n", report.NonIID.H_Final)
+	write_table := func(title string, results []estimate.Estimator_Result) {
+		fmt.Fprintf(&b, "## %s\n\n| 估计方法 | 最小熵 |\n| --- | --- |\n", title)
+		for _, r := range results {
+			if r.Applicable {
+				fmt.Fprintf(&b, "| %s | %.6f |\n", r.Name, r.Min_Entropy)
+			} else {
+				fmt.Fprintf(&b, "| %s | 不适用 |\n", r.Name)
+			}
+		}
+		b.WriteString("\n")
+	}
+	write_table(fmt.Sprintf("原始符号 (H_original = %.6f)", report.NonIID.H_Original), report.NonIID.Original)
+	if len(report.NonIID.Bitstring) > 0 {
+		write_table(fmt.Sprintf("比特串 (H_bitstring = %.6f)", report.NonIID.H_Bitstring), report.NonIID.Bitstring)
+	}
+	if report.IID != nil {
+		fmt.Fprintf(&b, "## IID检验 (%s)\n\n| 检验 | 统计量 | 结果 |\n| --- | --- | --- |\n", pass_string(report.IID.Pass))
+		for _, r := range report.IID.Permutation {
+			fmt.Fprintf(&b, "| %s | C0=%d C1=%d | %s |\n", r.Name, r.C0, r.C1, pass_string(r.Pass))
+		}
+		for _, r := range report.IID.Chi_Square {
+			fmt.Fprintf(&b, "| chiSquare %s | T=%.4f df=%d P=%.6f | %s |\n", r.Name, r.T, r.Df, r.P_Value, pass_string(r.Pass))
+		}
+		fmt.Fprintf(&b, "| LRS | W=%d P=%.6f | %s |\n", report.IID.LRS.W, report.IID.LRS.P, pass_string(report.IID.LRS.Pass))
+	}
+	return b.String()
+}
+
+// 检验结果->字符串
+func pass_string(pass bool) string {
+	if pass {
+		return "通过"
+	}
+	return "未通过"
+}
+
+func main() {
+	bits_per_symbol := flag.Int("bits", 8, "每个符号的比特数(1-8)")
+	output := flag.String("o", ".", "报告输出目录")
+	format := flag.String("format", "both", "报告格式: json、markdown或both")
+	iid := flag.Bool("iid", false, "同时运行IID检验")
+	shuffles := flag.Int("shuffles", 10000, "IID置换检验的洗牌次数")
+	seed := flag.Uint64("seed", 0, "IID置换检验的固定种子(0表示随机)")
+	flag.Parse()
+
+	if flag.NArg() == 0 || *bits_per_symbol < 1 || *bits_per_symbol > 8 {
+		fmt.Fprintln(os.Stderr, "用法: entropy-assess [选项] 样本文件...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	failed := false
+	for _, file := range flag.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil || len(data) == 0 {
+			fmt.Fprintln(os.Stderr, "读取样本文件失败:", file, err)
+			failed = true
+			continue
+		}
+		mask := byte(1<<*bits_per_symbol - 1)
+		for i := range data {
+			data[i] &= mask
+		}
+		source := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		report := Report{Source: source, File: file, NonIID: estimate.NonIID_Suite(data, *bits_per_symbol)}
+		if *iid {
+			config := estimate.Default_IID_Config()
+			config.Shuffles = *shuffles
+			config.Deterministic = *seed != 0
+			config.Seed = *seed
+			result := estimate.IID_Tests(data, config)
+			report.IID = &result
+		}
+		base := filepath.Join(*output, source)
+		if *format == "json" || *format == "both" {
+			bytes, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "生成JSON报告失败:", source, err)
+				failed = true
+			} else if err := os.WriteFile(base+".json", append(bytes, '\n'), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, "写入报告失败:", err)
+				failed = true
+			}
+		}
+		if *format == "markdown" || *format == "both" {
+			if err := os.WriteFile(base+".md", []byte(report.Markdown()), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, "写入报告失败:", err)
+				failed = true
+			}
+		}
+		fmt.Printf("%s: %.6f 比特/符号\n", source, report.NonIID.H_Final)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,44 @@
+// randtest-judge按GM/T 0005-2021对样本集进行随机性检测与判定,样本来自文件或直接来自DRBG
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+	"github.com/jellygdh/drbg_sm3/randtest"
+)
+
+func main() {
+	config := randtest.Default_Judge_Config()
+	file := flag.String("file", "", "样本文件(为空时直接从DRBG读取)")
+	mode := flag.Int("mode", 3, "DRBG工作模式(0-3)")
+	flag.IntVar(&config.Samples, "samples", config.Samples, "样本个数")
+	flag.IntVar(&config.Sample_Bits, "bits", config.Sample_Bits, "样本长度(单位:比特)")
+	flag.IntVar(&config.Workers, "workers", config.Workers, "并行的goroutine数量")
+	flag.Parse()
+
+	var reader io.Reader
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "打开样本文件失败:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		reader = f
+	} else {
+		reader = drbg.Init_DRBG_SM3(*mode, "")
+	}
+	report, err := randtest.Judge(reader, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "样本集判定失败:", err)
+		os.Exit(1)
+	}
+	fmt.Print(report)
+	if !report.Pass {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+// smallcrush对32比特字输出运行SmallCrush式经验检测,样本来自文件或直接来自DRBG
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jellygdh/drbg_sm3/crush"
+	"github.com/jellygdh/drbg_sm3/drbg"
+)
+
+func main() {
+	file := flag.String("file", "", "样本文件(为空时直接从DRBG读取)")
+	mode := flag.Int("mode", 3, "DRBG工作模式(0-3)")
+	quick := flag.Bool("quick", false, "运行快速检测组(样本量约为SmallCrush的十分之一)")
+	flag.Parse()
+
+	var reader io.Reader
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "打开样本文件失败:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		reader = f
+	} else {
+		reader = drbg.Init_DRBG_SM3(*mode, "")
+	}
+	tests := crush.Small_Crush()
+	if *quick {
+		tests = crush.Quick_Crush()
+	}
+	report, err := crush.Run(reader, tests)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "检测失败:", err)
+		os.Exit(1)
+	}
+	fmt.Print(report)
+	if !report.Pass {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+// Package crush 参照TestU01 SmallCrush实现面向32比特字的经验检测,用于发现字输出的结构性缺陷
+package crush
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	min_expected  = 5     //卡方检测中每个分类的最小期望数,不足时与相邻分类合并
+	suspect_low   = 0.001 //P值低于该值视为可疑
+	suspect_high  = 0.999 //P值高于该值视为可疑
+	word_bits     = 32    //字长度(单位:比特)
+	word_bytes    = word_bits / 8
+	reader_buffer = 1 << 16
+)
+
+// 32比特字读取器,按大端序从io.Reader读取
+type Word_Reader struct {
+	reader *bufio.Reader
+	buffer [word_bytes]byte
+	Count  int64 //已读取的字数
+	Err    error //首次读取错误,出错后返回0
+}
+
+// 单项检测结果
+type Result struct {
+	Name      string  //检测名称(含参数)
+	Statistic float64 //统计量
+	P_Value   float64 //P值
+}
+
+// 检测项
+type Test struct {
+	Name string
+	Run  func(reader *Word_Reader) Result
+}
+
+// 检测报告
+type Report struct {
+	Results []Result //各项检测结果
+	Words   int64    //消耗的字数
+	Pass    bool     //是否全部通过
+}
+
+// 创建字读取器
+func New_Word_Reader(reader io.Reader) *Word_Reader {
+	return &Word_Reader{reader: bufio.NewReaderSize(reader, reader_buffer)}
+}
+
+// 读取一个32比特字
+func (reader *Word_Reader) Uint32() uint32 {
+	if reader.Err != nil {
+		return 0
+	}
+	if _, err := io.ReadFull(reader.reader, reader.buffer[:]); err != nil {
+		reader.Err = err
+		return 0
+	}
+	reader.Count++
+	return binary.BigEndian.Uint32(reader.buffer[:])
+}
+
+// 读取一个字,丢弃最高的r比特后取接下来的s比特
+func (reader *Word_Reader) Bits(r int, s int) uint32 {
+	return reader.Uint32() << r >> (word_bits - s)
+}
+
+// 读取一个字,丢弃最高的r比特后作为[0,1)上的均匀分布随机数
+func (reader *Word_Reader) Uniform(r int) float64 {
+	return float64(reader.Bits(r, word_bits-r)) / math.Exp2(float64(word_bits-r))
+}
+
+// 判断P值是否可疑
+func (result Result) Suspect() bool {
+	return math.IsNaN(result.P_Value) || result.P_Value < suspect_low || result.P_Value > suspect_high
+}
+
+// 卡方检测,期望数不足min_expected的分类与相邻分类合并,返回统计量与P值
+func chi_square_test(observed []float64, expected []float64) (float64, float64) {
+	var merged_observed, merged_expected []float64
+	o, e := 0.0, 0.0
+	for i := range expected {
+		o += observed[i]
+		e += expected[i]
+		if e >= min_expected {
+			merged_observed = append(merged_observed, o)
+			merged_expected = append(merged_expected, e)
+			o, e = 0, 0
+		}
+	}
+	if len(merged_expected) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	merged_observed[len(merged_observed)-1] += o
+	merged_expected[len(merged_expected)-1] += e
+	if len(merged_expected) < 2 {
+		return math.NaN(), math.NaN()
+	}
+	X := 0.0
+	for i := range merged_expected {
+		X += (merged_observed[i] - merged_expected[i]) * (merged_observed[i] - merged_expected[i]) / merged_expected[i]
+	}
+	return X, tools.Igamc(float64(len(merged_expected)-1)/2, X/2)
+}
+
+// 泊松分布的右尾概率P(X>=x)
+func poisson_upper(x int, lambda float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return tools.Igam(float64(x), lambda)
+}
+
+// 从reader读取32比特字并依次运行检测
+func Run(reader io.Reader, tests []Test) (Report, error) {
+	words := New_Word_Reader(reader)
+	report := Report{Pass: true}
+	for _, test := range tests {
+		result := test.Run(words)
+		if words.Err != nil {
+			return report, fmt.Errorf("%s: %w", test.Name, words.Err)
+		}
+		report.Results = append(report.Results, result)
+		report.Pass = report.Pass && !result.Suspect()
+	}
+	report.Words = words.Count
+	return report, nil
+}
+
+// 检测报告->文本
+func (report Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "消耗字数: %d\n", report.Words)
+	failed := 0
+	for i, result := range report.Results {
+		status := "通过"
+		if result.Suspect() {
+			status = "可疑"
+			failed++
+		}
+		fmt.Fprintf(&b, "%2d  %-48s  %14.4f  %.6f  %s\n", i+1, result.Name, result.Statistic, result.P_Value, status)
+	}
+	if report.Pass {
+		b.WriteString("全部检测通过\n")
+	} else {
+		fmt.Fprintf(&b, "%d项检测的P值超出[%g,%g]\n", failed, suspect_low, suspect_high)
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package crush
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+)
+
+// 模2^32的线性同余发生器x=69069x+1,输出整个状态;低位周期极短(第i位周期为2^(i+1)),用作弱发生器
+type lcg_reader struct {
+	state uint32
+}
+
+func (lcg *lcg_reader) Read(p []byte) (int, error) {
+	n := len(p) / word_bytes * word_bytes
+	for i := 0; i < n; i += word_bytes {
+		lcg.state = lcg.state*69069 + 1
+		binary.BigEndian.PutUint32(p[i:], lcg.state)
+	}
+	return n, nil
+}
+
+// 快速检测组自检:以固定种子的SM3 Hash_DRBG为随机源,各项检测均应通过;
+// 以线性同余发生器为随机源,应有检测结果可疑
+func Test_Quick_Crush() int {
+	seed := []byte("crush self-test")
+	report, err := Run(bufio.NewReaderSize(drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil), reader_buffer), Quick_Crush())
+	if err != nil || !report.Pass {
+		fmt.Println("Test_Quick_Crush error: Hash_DRBG", err)
+		fmt.Print(report)
+		return -1
+	}
+	report, err = Run(&lcg_reader{state: 1}, Quick_Crush())
+	if err != nil || report.Pass {
+		fmt.Println("Test_Quick_Crush error: LCG", err)
+		fmt.Print(report)
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,345 @@
+package crush
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/jellygdh/drbg_sm3/randtest"
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+// 生日间隔检测:n个点落入d^t个格子(d=2^b),统计排序后间隔的重复数,近似服从均值n^3/(4k)的泊松分布
+func Birthday_Spacings(reader *Word_Reader, n int, r int, b int, t int) Result {
+	name := fmt.Sprintf("生日间隔检测(n=%d,r=%d,d=2^%d,t=%d)", n, r, b, t)
+	cells := make([]uint64, n)
+	for i := range cells {
+		for j := 0; j < t; j++ {
+			cells[i] = cells[i]<<b | uint64(reader.Bits(r, b))
+		}
+	}
+	slices.Sort(cells)
+	spacings := make([]uint64, n-1)
+	for i := range spacings {
+		spacings[i] = cells[i+1] - cells[i]
+	}
+	slices.Sort(spacings)
+	Y := 0
+	for i := 1; i < len(spacings); i++ {
+		if spacings[i] == spacings[i-1] {
+			Y++
+		}
+	}
+	nf := float64(n)
+	lambda := nf * nf * nf / (4 * math.Exp2(float64(b*t)))
+	return Result{name, float64(Y), poisson_upper(Y, lambda)}
+}
+
+// 碰撞检测:n个点落入d^t个格子(d=2^b),碰撞数近似服从泊松分布
+func Collision(reader *Word_Reader, n int, r int, b int, t int) Result {
+	name := fmt.Sprintf("碰撞检测(n=%d,r=%d,d=2^%d,t=%d)", n, r, b, t)
+	cells := make([]uint64, n)
+	for i := range cells {
+		for j := 0; j < t; j++ {
+			cells[i] = cells[i]<<b | uint64(reader.Bits(r, b))
+		}
+	}
+	slices.Sort(cells)
+	C := 0
+	for i := 1; i < n; i++ {
+		if cells[i] == cells[i-1] {
+			C++
+		}
+	}
+	k := math.Exp2(float64(b * t))
+	nf := float64(n)
+	mu := nf - k + k*math.Exp(nf*math.Log1p(-1/k))
+	return Result{name, float64(C), poisson_upper(C, mu)}
+}
+
+// 间隔检测:统计相邻两次落入[alpha,beta)之间的间隔长度,服从几何分布
+func Gap(reader *Word_Reader, n int, r int, alpha float64, beta float64) Result {
+	name := fmt.Sprintf("间隔检测(n=%d,r=%d,[%g,%g))", n, r, alpha, beta)
+	p := beta - alpha
+	//尾部分类的期望数不小于min_expected
+	t := int(math.Log(min_expected/float64(n)) / math.Log1p(-p))
+	observed := make([]float64, t+1)
+	for i := 0; i < n; i++ {
+		gap := 0
+		for {
+			u := reader.Uniform(r)
+			if (u >= alpha && u < beta) || reader.Err != nil {
+				break
+			}
+			gap++
+		}
+		observed[min(gap, t)]++
+	}
+	expected := make([]float64, t+1)
+	for g := 0; g < t; g++ {
+		expected[g] = float64(n) * p * math.Pow(1-p, float64(g))
+	}
+	expected[t] = float64(n) * math.Pow(1-p, float64(t))
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// k次从d个值中等概率抽取后不同值个数的分布
+func distinct_distribution(d int, k int) []float64 {
+	p := make([]float64, d+1)
+	p[0] = 1
+	for j := 0; j < k; j++ {
+		for s := min(j+1, d); s >= 1; s-- {
+			p[s] = p[s]*float64(s)/float64(d) + p[s-1]*float64(d-s+1)/float64(d)
+		}
+		p[0] = 0
+	}
+	return p
+}
+
+// 简单扑克检测:每组k个取值于d个值(d=2^b)的数,统计组内不同值的个数
+func Simple_Poker(reader *Word_Reader, n int, r int, b int, k int) Result {
+	d := 1 << b
+	name := fmt.Sprintf("简单扑克检测(n=%d,r=%d,d=%d,k=%d)", n, r, d, k)
+	observed := make([]float64, d+1)
+	seen := make([]int, d)
+	for i := 1; i <= n; i++ {
+		distinct := 0
+		for j := 0; j < k; j++ {
+			value := reader.Bits(r, b)
+			if seen[value] != i {
+				seen[value] = i
+				distinct++
+			}
+		}
+		observed[distinct]++
+	}
+	expected := distinct_distribution(d, k)
+	for s := range expected {
+		expected[s] *= float64(n)
+	}
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// 集齐全部d个值所需抽取次数的分布,返回P(T=t)(t<len-1)与尾部P(T>=len-1),尾部期望数不小于min_expected
+func coupon_distribution(d int, n int) []float64 {
+	p := make([]float64, d+1)
+	p[0] = 1
+	var distribution []float64
+	tail := 1.0
+	for t := 1; ; t++ {
+		//第t次抽取恰好集齐
+		P_t := p[d-1] / float64(d)
+		for s := min(t, d); s >= 1; s-- {
+			p[s] = p[s]*float64(s)/float64(d) + p[s-1]*float64(d-s+1)/float64(d)
+		}
+		p[0] = 0
+		p[d] = 0
+		if t < d {
+			distribution = append(distribution, 0)
+			continue
+		}
+		if (tail-P_t)*float64(n) < min_expected {
+			return append(distribution, tail)
+		}
+		distribution = append(distribution, P_t)
+		tail -= P_t
+	}
+}
+
+// 集券检测:统计集齐全部d个值(d=2^b)所需的抽取次数
+func Coupon_Collector(reader *Word_Reader, n int, r int, b int) Result {
+	d := 1 << b
+	name := fmt.Sprintf("集券检测(n=%d,r=%d,d=%d)", n, r, d)
+	expected := coupon_distribution(d, n)
+	t_max := len(expected)
+	observed := make([]float64, t_max)
+	seen := make([]int, d)
+	for i := 1; i <= n; i++ {
+		collected, t := 0, 0
+		for collected < d && reader.Err == nil {
+			value := reader.Bits(r, b)
+			t++
+			if seen[value] != i {
+				seen[value] = i
+				collected++
+			}
+		}
+		observed[min(t, t_max)-1]++
+	}
+	for i := range expected {
+		expected[i] *= float64(n)
+	}
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// t个均匀分布随机数的最大值检测:最大值的t次方服从[0,1)上的均匀分布,划分为d个区间
+func Max_Of_T(reader *Word_Reader, n int, r int, d int, t int) Result {
+	name := fmt.Sprintf("最大值检测(n=%d,r=%d,d=%d,t=%d)", n, r, d, t)
+	observed := make([]float64, d)
+	for i := 0; i < n; i++ {
+		maximum := 0.0
+		for j := 0; j < t; j++ {
+			maximum = max(maximum, reader.Uniform(r))
+		}
+		observed[min(int(math.Pow(maximum, float64(t))*float64(d)), d-1)]++
+	}
+	expected := make([]float64, d)
+	for i := range expected {
+		expected[i] = float64(n) / float64(d)
+	}
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// 二项分布B(k,p)的概率分布
+func binomial_distribution(k int, p float64) []float64 {
+	distribution := make([]float64, k+1)
+	lg_k, _ := math.Lgamma(float64(k + 1))
+	for i := range distribution {
+		lg_i, _ := math.Lgamma(float64(i + 1))
+		lg_ki, _ := math.Lgamma(float64(k - i + 1))
+		distribution[i] = math.Exp(lg_k - lg_i - lg_ki + float64(i)*math.Log(p) + float64(k-i)*math.Log1p(-p))
+	}
+	return distribution
+}
+
+// 权重分布检测:每组k个均匀分布随机数中落入[alpha,beta)的个数服从二项分布
+func Weight_Distribution(reader *Word_Reader, n int, r int, k int, alpha float64, beta float64) Result {
+	name := fmt.Sprintf("权重分布检测(n=%d,r=%d,k=%d,[%g,%g))", n, r, k, alpha, beta)
+	observed := make([]float64, k+1)
+	for i := 0; i < n; i++ {
+		weight := 0
+		for j := 0; j < k; j++ {
+			if u := reader.Uniform(r); u >= alpha && u < beta {
+				weight++
+			}
+		}
+		observed[weight]++
+	}
+	expected := binomial_distribution(k, beta-alpha)
+	for i := range expected {
+		expected[i] *= float64(n)
+	}
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// 读取L比特,每个字取丢弃最高r比特后的s比特,以uint64切片表示(高位在前)
+func read_row(reader *Word_Reader, r int, s int, L int) []uint64 {
+	row := make([]uint64, (L+63)/64)
+	for c := 0; c < L; {
+		value := reader.Bits(r, s)
+		for j := s - 1; j >= 0 && c < L; j-- {
+			if value>>j&1 == 1 {
+				row[c/64] |= 1 << (c % 64)
+			}
+			c++
+		}
+	}
+	return row
+}
+
+// 矩阵秩检测:n个L×k随机二元矩阵的秩分布
+func Matrix_Rank(reader *Word_Reader, n int, r int, s int, L int, k int) Result {
+	name := fmt.Sprintf("矩阵秩检测(n=%d,r=%d,s=%d,L=%d,k=%d)", n, r, s, L, k)
+	full := min(L, k)
+	observed := make([]float64, full+1)
+	for i := 0; i < n; i++ {
+		rows := make([][]uint64, L)
+		for j := range rows {
+			rows[j] = read_row(reader, r, s, k)
+		}
+		observed[randtest.Binary_Rank(rows, k)]++
+	}
+	expected := make([]float64, full+1)
+	for rank := range expected {
+		expected[rank] = float64(n) * randtest.Rank_Probability(rank, L, k)
+	}
+	X, P := chi_square_test(observed, expected)
+	return Result{name, X, P}
+}
+
+// 按二项分布B(L,1/2)将汉明重量划分为概率大致相等的classes个分类,返回各重量所属分类与各分类的概率
+func weight_classes(L int, classes int) ([]int, []float64) {
+	distribution := binomial_distribution(L, 0.5)
+	class_of := make([]int, L+1)
+	var probabilities []float64
+	cumulative, current := 0.0, 0.0
+	for w, p := range distribution {
+		class_of[w] = len(probabilities)
+		cumulative += p
+		current += p
+		if cumulative >= float64(len(probabilities)+1)/float64(classes) && w < L {
+			probabilities = append(probabilities, current)
+			current = 0
+		}
+	}
+	probabilities = append(probabilities, current)
+	return class_of, probabilities
+}
+
+// 汉明独立性检测:相邻两个L比特块的汉明重量(按分类)的二维列联表卡方检测
+func Hamming_Independence(reader *Word_Reader, n int, r int, s int, L int) Result {
+	name := fmt.Sprintf("汉明独立性检测(n=%d,r=%d,s=%d,L=%d)", n, r, s, L)
+	class_of, probabilities := weight_classes(L, 8)
+	c := len(probabilities)
+	observed := make([]float64, c*c)
+	weight := func() int {
+		w := 0
+		for _, word := range read_row(reader, r, s, L) {
+			w += bits.OnesCount64(word)
+		}
+		return w
+	}
+	pairs := n / 2
+	for i := 0; i < pairs; i++ {
+		first := class_of[weight()]
+		second := class_of[weight()]
+		observed[first*c+second]++
+	}
+	expected := make([]float64, c*c)
+	for i := 0; i < c; i++ {
+		for j := 0; j < c; j++ {
+			expected[i*c+j] = float64(pairs) * probabilities[i] * probabilities[j]
+		}
+	}
+	X := 0.0
+	for i := range expected {
+		X += (observed[i] - expected[i]) * (observed[i] - expected[i]) / expected[i]
+	}
+	return Result{name, X, tools.Igamc(float64(c*c-1)/2, X/2)}
+}
+
+// SmallCrush参数的检测组
+func Small_Crush() []Test {
+	return []Test{
+		{"BirthdaySpacings", func(reader *Word_Reader) Result { return Birthday_Spacings(reader, 5000000, 0, 30, 2) }},
+		{"Collision", func(reader *Word_Reader) Result { return Collision(reader, 5000000, 0, 16, 2) }},
+		{"Gap", func(reader *Word_Reader) Result { return Gap(reader, 200000, 22, 0, 1.0/256) }},
+		{"SimplePoker", func(reader *Word_Reader) Result { return Simple_Poker(reader, 400000, 24, 6, 64) }},
+		{"CouponCollector", func(reader *Word_Reader) Result { return Coupon_Collector(reader, 500000, 26, 4) }},
+		{"MaxOft", func(reader *Word_Reader) Result { return Max_Of_T(reader, 2000000, 0, 100000, 6) }},
+		{"WeightDistrib", func(reader *Word_Reader) Result { return Weight_Distribution(reader, 200000, 27, 256, 0, 0.125) }},
+		{"MatrixRank", func(reader *Word_Reader) Result { return Matrix_Rank(reader, 20000, 20, 10, 60, 60) }},
+		{"HammingIndep", func(reader *Word_Reader) Result { return Hamming_Independence(reader, 500000, 20, 10, 300) }},
+	}
+}
+
+// 快速检测组,样本量约为SmallCrush的十分之一,用于输出函数修改后的回归检测
+func Quick_Crush() []Test {
+	return []Test{
+		{"BirthdaySpacings", func(reader *Word_Reader) Result { return Birthday_Spacings(reader, 1000000, 0, 26, 2) }},
+		{"Collision", func(reader *Word_Reader) Result { return Collision(reader, 500000, 0, 14, 2) }},
+		{"Gap", func(reader *Word_Reader) Result { return Gap(reader, 20000, 22, 0, 1.0/256) }},
+		{"SimplePoker", func(reader *Word_Reader) Result { return Simple_Poker(reader, 40000, 24, 6, 64) }},
+		{"CouponCollector", func(reader *Word_Reader) Result { return Coupon_Collector(reader, 50000, 26, 4) }},
+		{"MaxOft", func(reader *Word_Reader) Result { return Max_Of_T(reader, 200000, 0, 10000, 6) }},
+		{"WeightDistrib", func(reader *Word_Reader) Result { return Weight_Distribution(reader, 20000, 27, 256, 0, 0.125) }},
+		{"MatrixRank", func(reader *Word_Reader) Result { return Matrix_Rank(reader, 2000, 20, 10, 60, 60) }},
+		{"HammingIndep", func(reader *Word_Reader) Result { return Hamming_Independence(reader, 50000, 20, 10, 300) }},
+	}
+}
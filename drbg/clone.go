@@ -0,0 +1,212 @@
+package drbg
+
+import (
+	"bytes"
+	crypto_rand "crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	boot_id_file            = "/proc/sys/kernel/random/boot_id" //Linux启动标识,每次启动随机生成
+	identity_check_interval = 100 * time.Millisecond            //重新读取标识文件的最小间隔
+	fresh_input_length      = 32                                //每次输出混入的新鲜随机数长度(单位:字节)
+)
+
+// 虚拟机代数计数器(VM generation ID)文件路径,默认为空。
+// Linux没有通用的用户态VM generation ID文件(vmgenid驱动只用于内核自身的随机数重播种),
+// 而快照恢复不改变boot_id,因此为空时无法发现快照恢复,改为在每次输出时混入crypto/rand的新鲜随机数
+// (内核在快照恢复后会重播种),使各副本的输出不同。
+// 设置为虚拟化平台提供的、快照恢复或克隆后会改变的文件时,以该文件检测克隆,不再逐次混入
+var VM_Generation_File = ""
+
+// 进程标识:进程号、启动标识与虚拟机代数计数器,任一改变即认为DRBG状态可能已被复制
+type clone_identity struct {
+	pid           int
+	boot_id       []byte
+	vm_generation []byte
+}
+
+// 标识文件内容的缓存,每identity_check_interval至多重新读取一次
+var identity_cache struct {
+	mutex              sync.Mutex
+	read_at            time.Time //上次读取的时间(含单调时钟)
+	vm_generation_file string    //上次读取时的VM_Generation_File
+	boot_id            []byte
+	vm_generation      []byte
+}
+
+// 读取文件内容,不存在或不可读时返回nil
+func read_identity_file(path string) []byte {
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return bytes.TrimSpace(content)
+}
+
+// 获取当前进程标识。进程号每次获取;标识文件在距上次读取不足identity_check_interval时使用缓存,
+// 快照恢复后单调时钟可能不前进,因此同时比较墙上时间,任一超过间隔或墙上时间回退即重新读取
+func current_identity() clone_identity {
+	identity_cache.mutex.Lock()
+	defer identity_cache.mutex.Unlock()
+	now := time.Now()
+	monotonic := now.Sub(identity_cache.read_at)
+	wall := now.Round(0).Sub(identity_cache.read_at.Round(0))
+	if identity_cache.read_at.IsZero() || monotonic >= identity_check_interval || wall >= identity_check_interval || wall < 0 ||
+		identity_cache.vm_generation_file != VM_Generation_File {
+		identity_cache.boot_id = read_identity_file(boot_id_file)
+		identity_cache.vm_generation = read_identity_file(VM_Generation_File)
+		identity_cache.vm_generation_file = VM_Generation_File
+		identity_cache.read_at = now
+	}
+	return clone_identity{
+		pid:           os.Getpid(),
+		boot_id:       identity_cache.boot_id,
+		vm_generation: identity_cache.vm_generation,
+	}
+}
+
+func (identity clone_identity) equal(other clone_identity) bool {
+	return identity.pid == other.pid && bytes.Equal(identity.boot_id, other.boot_id) && bytes.Equal(identity.vm_generation, other.vm_generation)
+}
+
+// 编码为附加输入
+func (identity clone_identity) bytes() []byte {
+	return slices.Concat([]byte(strconv.Itoa(identity.pid)), []byte{0x00}, identity.boot_id, []byte{0x00}, identity.vm_generation)
+}
+
+// 检测进程分叉或克隆:进程标识与初始化(或上次重播种)时不同时,
+// 以新的熵输入、nonce和新进程标识作为附加输入进行完全重播种。
+// 被复制的熵池内容相同,因此须混入nonce中的时间戳与新鲜熵以区分各副本,无法获取nonce时进入错误状态。
+// 尚未记录进程标识的状态仅记录当前标识
+func (working_state *Working_State) check_clone() {
+	identity := current_identity()
+	if working_state.identity.pid == 0 {
+		working_state.identity = identity
+		return
+	}
+	if working_state.identity.equal(identity) {
+		return
+	}
+	provider := working_state.provider()
+	nonce := provider.Nonce()
+	if nonce == nil {
+		fmt.Println("check_clone error: nonce unavailable")
+		working_state.Error_State = true
+		return
+	}
+	working_state.SM3_DRBG_Reseed(provider.Entropy_Input(), slices.Concat(nonce, identity.bytes()))
+	working_state.Clone_Reseeds++
+}
+
+// 未设置VM_Generation_File时,使用熵池的状态每次输出前取fresh_input_length字节的crypto/rand随机数作为附加输入;
+// 注入提供者的状态(仅用于测试)不混入,以保持输出可复现。读取失败时返回false
+func (working_state *Working_State) fresh_input() ([]byte, bool) {
+	if VM_Generation_File != "" || working_state.entropy_provider != nil {
+		return nil, true
+	}
+	fresh := make([]byte, fresh_input_length)
+	if _, err := crypto_rand.Read(fresh); err != nil {
+		fmt.Println("fresh_input error:", err)
+		return nil, false
+	}
+	return fresh, true
+}
+
+// 克隆检测自检:复制一份已初始化的状态并令其记录的进程标识失效,模拟快照恢复后的副本,
+// 检查副本在下一次输出前完成重播种且输出与原状态不同,无法获取nonce的副本进入错误状态;
+// 未设置VM_Generation_File时每次输出混入新鲜随机数;
+// 并改写临时的虚拟机代数计数器文件,检查缓存在identity_check_interval后读到新值
+func Test_Clone_Detection() int {
+	if test_vm_generation() == -1 {
+		return -1
+	}
+	provider := func() *Recorded_Provider {
+		return &Recorded_Provider{
+			Entropy_Inputs: [][]byte{SM3_Hash.sum([]byte("entropy")), SM3_Hash.sum([]byte("entropy"))},
+			Nonces:         [][]byte{SM3_Hash.sum([]byte("nonce 0"))[:16], SM3_Hash.sum([]byte("nonce 1"))[:16]},
+		}
+	}
+	original := new_test_working_state(provider())
+	V, C := SM3_Hash.instantiate(original.entropy_provider.Entropy_Input(), original.entropy_provider.Nonce(), nil)
+	original.New_WorkingState(V, C, 1, 0)
+	original.identity = current_identity()
+	clone := *original
+	clone.V, clone.C = slices.Clone(V), slices.Clone(C)
+	clone.entropy_provider = provider()
+	clone.identity.pid = -1
+	first := original.sm3_drbg_generate(outlen, "")
+	second := clone.sm3_drbg_generate(outlen, "")
+	if original.Clone_Reseeds != 0 || clone.Clone_Reseeds != 1 || slices.Equal(first, second) {
+		return -1
+	}
+	//无法获取nonce时副本进入错误状态而不是以空nonce重播种
+	no_nonce := *original
+	no_nonce.entropy_provider = &Recorded_Provider{Entropy_Inputs: [][]byte{SM3_Hash.sum([]byte("entropy"))}}
+	no_nonce.identity.pid = -1
+	if no_nonce.sm3_drbg_generate(outlen, "") != nil || !no_nonce.Error_State || no_nonce.Clone_Reseeds != 0 {
+		return -1
+	}
+	return test_fresh_input()
+}
+
+// 未设置VM_Generation_File时,进程标识相同的两份默认状态(模拟虚拟机快照恢复)输出不同;
+// 设置后不再混入,两份状态输出相同
+func test_fresh_input() int {
+	saved := VM_Generation_File
+	defer func() { VM_Generation_File = saved }()
+	outputs := func() ([]byte, []byte) {
+		original := &Working_State{}
+		V, C := SM3_Hash.instantiate(SM3_Hash.sum([]byte("entropy")), SM3_Hash.sum([]byte("nonce"))[:16], nil)
+		original.New_WorkingState(V, C, 1, int(time.Now().Unix()))
+		original.identity = current_identity()
+		clone := *original
+		clone.V, clone.C = slices.Clone(V), slices.Clone(C)
+		return original.sm3_drbg_generate(outlen, ""), clone.sm3_drbg_generate(outlen, "")
+	}
+	VM_Generation_File = ""
+	first, second := outputs()
+	if first == nil || slices.Equal(first, second) {
+		return -1
+	}
+	VM_Generation_File = boot_id_file
+	first, second = outputs()
+	if first == nil || !slices.Equal(first, second) {
+		return -1
+	}
+	return 0
+}
+
+// 虚拟机代数计数器改变后,current_identity在一个检查间隔内反映新值
+func test_vm_generation() int {
+	dir, err := os.MkdirTemp("", "vmgenid")
+	if err != nil {
+		return -1
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vm_generation")
+	saved := VM_Generation_File
+	defer func() { VM_Generation_File = saved }()
+	if os.WriteFile(path, []byte("1"), 0600) != nil {
+		return -1
+	}
+	VM_Generation_File = path
+	before := current_identity()
+	if os.WriteFile(path, []byte("2"), 0600) != nil {
+		return -1
+	}
+	time.Sleep(identity_check_interval)
+	if before.equal(current_identity()) {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,272 @@
+package drbg
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jellygdh/drbg_sm3/sm4"
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	ctr_keylen   = 128                       //SM4密钥长度(单位:比特)
+	ctr_blocklen = 128                       //SM4分组长度(单位:比特)
+	ctr_seedlen  = ctr_keylen + ctr_blocklen //CTR_DRBG种子长度(单位:比特)
+)
+
+// SM4标准测试向量自检(含10^6次迭代加密,耗时较长),每个进程只运行一次
+var sm4_self_test = sync.OnceValue(sm4.Test_Vectors)
+
+// CTR_DRBG内部状态结构体
+type CTR_Working_State struct {
+	V                []byte                                 //计数器,长度为ctr_blocklen
+	Key              []byte                                 //分组密码密钥,长度为ctr_keylen
+	Reseed_Counter   int                                    //重播种计数器值
+	Last_Reseed_Time int64                                  //重播种时间值(单位:秒)
+	Use_DF           bool                                   //是否使用派生函数
+	new_cipher       func(key []byte) (cipher.Block, error) //分组密码
+}
+
+// V=(V+1) mod 2^ctr_blocklen
+func ctr_increment(V []byte) {
+	for i := len(V) - 1; i >= 0; i-- {
+		V[i]++
+		if V[i] != 0 {
+			break
+		}
+	}
+}
+
+// 右侧补0至ctr_seedlen比特
+func ctr_pad(input []byte) []byte {
+	padded := make([]byte, ctr_seedlen/8)
+	copy(padded, input)
+	return padded
+}
+
+// 创建分组密码,失败时返回nil
+func (working_state *CTR_Working_State) block(key []byte) cipher.Block {
+	new_cipher := working_state.new_cipher
+	if new_cipher == nil {
+		new_cipher = sm4.NewCipher
+	}
+	block, err := new_cipher(key)
+	if err != nil {
+		fmt.Println("CTR_DRBG error:", err)
+		return nil
+	}
+	return block
+}
+
+// 分组链接函数BCC
+func ctr_bcc(block cipher.Block, data []byte) []byte {
+	chaining_value := make([]byte, block.BlockSize())
+	for i := 0; i < len(data); i += len(chaining_value) {
+		subtle.XORBytes(chaining_value, chaining_value, data[i:i+len(chaining_value)])
+		block.Encrypt(chaining_value, chaining_value)
+	}
+	return chaining_value
+}
+
+// 分组密码派生函数Block_Cipher_df,返回长度为number_of_bits_to_return的比特串
+func (working_state *CTR_Working_State) Block_Cipher_df(input_string []byte, number_of_bits_to_return int) []byte {
+	blocklen := ctr_blocklen / 8
+	S := slices.Concat(tools.Int2Bytes(len(input_string), 4), tools.Int2Bytes(number_of_bits_to_return/8, 4), input_string, []byte{0x80})
+	for len(S)%blocklen != 0 {
+		S = append(S, 0x00)
+	}
+	K := make([]byte, ctr_keylen/8)
+	for i := range K {
+		K[i] = byte(i)
+	}
+	block := working_state.block(K)
+	temp := make([]byte, 0, ctr_seedlen/8)
+	for i := 0; len(temp) < ctr_seedlen/8; i++ {
+		IV := make([]byte, blocklen)
+		copy(IV, tools.Int2Bytes(i, 4))
+		temp = append(temp, ctr_bcc(block, slices.Concat(IV, S))...)
+	}
+	block = working_state.block(temp[:ctr_keylen/8])
+	X := temp[ctr_keylen/8 : ctr_seedlen/8]
+	temp = make([]byte, 0, number_of_bits_to_return/8+blocklen)
+	for len(temp)*8 < number_of_bits_to_return {
+		block.Encrypt(X, X)
+		temp = append(temp, X...)
+	}
+	return temp[:number_of_bits_to_return/8]
+}
+
+// CTR_DRBG状态更新函数,provided_data长度为ctr_seedlen
+func (working_state *CTR_Working_State) CTR_DRBG_Update(provided_data []byte) {
+	block := working_state.block(working_state.Key)
+	temp := make([]byte, 0, ctr_seedlen/8+ctr_blocklen/8)
+	output := make([]byte, ctr_blocklen/8)
+	for len(temp) < ctr_seedlen/8 {
+		ctr_increment(working_state.V)
+		block.Encrypt(output, working_state.V)
+		temp = append(temp, output...)
+	}
+	temp = temp[:ctr_seedlen/8]
+	subtle.XORBytes(temp, temp, provided_data)
+	working_state.Key = temp[:ctr_keylen/8]
+	working_state.V = temp[ctr_keylen/8:]
+}
+
+// 生成种子材料:使用派生函数时为df(输入拼接),否则为entropy_input与补0后的附加数据异或
+func (working_state *CTR_Working_State) seed_material(entropy_input []byte, data ...[]byte) []byte {
+	if working_state.Use_DF {
+		return working_state.Block_Cipher_df(slices.Concat(append([][]byte{entropy_input}, data...)...), ctr_seedlen)
+	}
+	seed_material := ctr_pad(entropy_input)
+	for _, d := range data {
+		subtle.XORBytes(seed_material, seed_material, ctr_pad(d))
+	}
+	return seed_material
+}
+
+// 由熵输入、nonce与个性化字符串建立初始状态,不使用派生函数时忽略nonce
+func (working_state *CTR_Working_State) ctr_drbg_instantiate(entropy_input []byte, nonce []byte, personalization_string []byte) {
+	var seed_material []byte
+	if working_state.Use_DF {
+		seed_material = working_state.seed_material(entropy_input, nonce, personalization_string)
+	} else {
+		seed_material = working_state.seed_material(entropy_input, personalization_string)
+	}
+	working_state.Key = make([]byte, ctr_keylen/8)
+	working_state.V = make([]byte, ctr_blocklen/8)
+	working_state.CTR_DRBG_Update(seed_material)
+	working_state.Reseed_Counter = 1
+	working_state.Last_Reseed_Time = time.Now().Unix()
+}
+
+// 从熵池获取熵输入,不使用派生函数时以SM3_df压缩为ctr_seedlen比特
+func (working_state *CTR_Working_State) get_entropy() []byte {
+	i, entropy_input := Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	for i == -1 {
+		i, entropy_input = Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	}
+	if working_state.Use_DF {
+		return entropy_input
+	}
+	return SM3_df(entropy_input, ctr_seedlen)
+}
+
+// 初始化函数
+func (working_state *CTR_Working_State) CTR_DRBG_Instantiate(personalization_string string) {
+	if Test_KnownAnswer_CTR() == -1 {
+		fmt.Println("CTR_DRBG已知答案测试未通过!")
+	}
+	if !working_state.Use_DF && len(personalization_string)*8 > ctr_seedlen {
+		fmt.Println("CTR_DRBG_Instantiate error: personalization string too long")
+		personalization_string = personalization_string[:ctr_seedlen/8]
+	}
+	nonce, err := Get_Nonce()
+	if err != nil {
+		fmt.Println("CTR_DRBG_Instantiate error:", err)
+		return
+	}
+	min_entropy = min_entropy_input_length
+	working_state.ctr_drbg_instantiate(working_state.get_entropy(), nonce, []byte(personalization_string))
+}
+
+// 重播种函数
+func (working_state *CTR_Working_State) CTR_DRBG_Reseed(entropy_input []byte, addition_input []byte) {
+	working_state.CTR_DRBG_Update(working_state.seed_material(entropy_input, addition_input))
+	working_state.Reseed_Counter = 1
+	working_state.Last_Reseed_Time = time.Now().Unix()
+}
+
+// 输出函数,达到重播种计数器阈值或时间阈值时先从熵池重播种;已卸载时返回nil
+func (working_state *CTR_Working_State) CTR_DRBG_Generate(requested_number_of_bits int, addition_input string) []byte {
+	if working_state.Key == nil {
+		fmt.Println("CTR_DRBG未初始化!")
+		return nil
+	}
+	addition_input_bytes := []byte(addition_input)
+	if !working_state.Use_DF && len(addition_input_bytes)*8 > ctr_seedlen {
+		fmt.Println("CTR_DRBG_Generate error: additional input too long")
+		return nil
+	}
+	if working_state.Reseed_Counter > reseed_interval_in_counter || time.Now().Unix()-working_state.Last_Reseed_Time > reseed_interval_in_time {
+		working_state.CTR_DRBG_Reseed(working_state.get_entropy(), addition_input_bytes)
+		addition_input_bytes = nil
+	}
+	additional := make([]byte, ctr_seedlen/8)
+	if len(addition_input_bytes) != 0 {
+		additional = working_state.seed_material(addition_input_bytes)
+		working_state.CTR_DRBG_Update(additional)
+	}
+	block := working_state.block(working_state.Key)
+	temp := make([]byte, 0, requested_number_of_bits/8+ctr_blocklen/8)
+	output := make([]byte, ctr_blocklen/8)
+	for len(temp)*8 < requested_number_of_bits {
+		ctr_increment(working_state.V)
+		block.Encrypt(output, working_state.V)
+		temp = append(temp, output...)
+	}
+	working_state.CTR_DRBG_Update(additional)
+	working_state.Reseed_Counter++
+	return temp[:requested_number_of_bits/8]
+}
+
+// 卸载函数,清除内部状态
+func (working_state *CTR_Working_State) CTR_DRBG_Uninstantiate() {
+	clear(working_state.Key)
+	clear(working_state.V)
+	working_state.Key = nil
+	working_state.V = nil
+	working_state.Reseed_Counter = 0
+	working_state.Last_Reseed_Time = 0
+}
+
+// CTR_DRBG已知答案测试:先进行SM4标准测试向量自检,再分别测试使用与不使用派生函数两种情形,比对第二次输出
+func Test_KnownAnswer_CTR() int {
+	if sm4_self_test() == -1 {
+		fmt.Println("SM4标准测试向量自检未通过!")
+		return -1
+	}
+	nonce, _ := hex.DecodeString("20212223242526272829")
+	entropy_input, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	targets := map[bool]string{
+		true:  "77def154efbd1e5748d647ed4443af002756ceb7db753495da01aab0c24f1131",
+		false: "5980325530fff0d3aa84077d64fdd15d62a018931c969100a3643efdaa1274cd",
+	}
+	flag := 0
+	for _, use_df := range []bool{true, false} {
+		working_state := &CTR_Working_State{Use_DF: use_df}
+		working_state.ctr_drbg_instantiate(entropy_input, nonce, nil)
+		working_state.CTR_DRBG_Generate(256, "")
+		result := working_state.CTR_DRBG_Generate(256, "")
+		if hex.EncodeToString(result) != targets[use_df] {
+			flag = -1
+		}
+	}
+	return flag
+}
+
+// 初始化CTR_DRBG,use_df表示是否使用派生函数
+func Init_CTR_DRBG_SM4(Mode int, personalization_string string, use_df bool) *CTR_Working_State {
+	go Select_Mode(Mode)
+	working_state := &CTR_Working_State{Use_DF: use_df}
+	working_state.CTR_DRBG_Instantiate(personalization_string)
+	return working_state
+}
+
+// 实现io.Reader,按ctr_blocklen比特分组调用输出函数
+func (working_state *CTR_Working_State) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		random_bytes := working_state.CTR_DRBG_Generate(outlen, "")
+		if len(random_bytes) == 0 {
+			return n, errors.New("CTR_DRBG未初始化")
+		}
+		n += copy(p[n:], random_bytes)
+	}
+	return n, nil
+}
@@ -0,0 +1,167 @@
+package drbg
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"slices"
+	"strings"
+
+	"github.com/jellygdh/drbg_sm3/sm3"
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+// Hash_DRBG使用的杂凑函数
+type Hash_Function struct {
+	Name                 string           //杂凑函数名称
+	New                  func() hash.Hash //杂凑计算器
+	Seedlen              int              //种子长度(单位:比特)
+	Reseed_Entropy_First bool             //重播种时熵输入在V之前(GM/T 0105:0x01||entropy_input||V||additional_input),否则按SP 800-90A为0x01||V||entropy_input||additional_input
+}
+
+var SM3_Hash = Hash_Function{"SM3", sm3.New, seedlen, true}                       //SM3,seedlen=440,GM/T 0105重播种顺序
+var SHA1_Hash = Hash_Function{"SHA-1", sha1.New, 440, false}                      //SHA-1,seedlen=440
+var SHA224_Hash = Hash_Function{"SHA-224", sha256.New224, 440, false}             //SHA-224,seedlen=440
+var SHA256_Hash = Hash_Function{"SHA-256", sha256.New, 440, false}                //SHA-256,seedlen=440
+var SHA384_Hash = Hash_Function{"SHA-384", sha512.New384, 888, false}             //SHA-384,seedlen=888
+var SHA512_Hash = Hash_Function{"SHA-512", sha512.New, 888, false}                //SHA-512,seedlen=888
+var SHA512_224_Hash = Hash_Function{"SHA-512/224", sha512.New512_224, 440, false} //SHA-512/224,seedlen=440
+var SHA512_256_Hash = Hash_Function{"SHA-512/256", sha512.New512_256, 440, false} //SHA-512/256,seedlen=440
+
+// 支持的杂凑函数
+var Hash_Functions = []Hash_Function{SM3_Hash, SHA1_Hash, SHA224_Hash, SHA256_Hash, SHA384_Hash, SHA512_Hash, SHA512_224_Hash, SHA512_256_Hash}
+
+// 按名称查找杂凑函数,兼容ACVP的"SHA2-256"写法
+func Find_Hash_Function(name string) (Hash_Function, bool) {
+	name = strings.Replace(strings.ToUpper(strings.TrimSpace(name)), "SHA2-", "SHA-", 1)
+	for _, h := range Hash_Functions {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return Hash_Function{}, false
+}
+
+const hash_drbg_max_request = 1 << 16 //单次输出的最大长度(单位:字节),不超过SP 800-90A规定的2^19比特
+
+// 与确定性输入配合使用的Hash_DRBG,不访问熵池,用于测试向量验证
+type Hash_DRBG struct {
+	Hash           Hash_Function //杂凑函数
+	V              []byte        //比特串,长度为seedlen
+	C              []byte        //常量,长度为seedlen
+	Reseed_Counter int           //重播种计数器值
+}
+
+// 杂凑函数输出
+func (h Hash_Function) sum(data ...[]byte) []byte {
+	digest := h.New()
+	for _, d := range data {
+		digest.Write(d)
+	}
+	return digest.Sum(nil)
+}
+
+// 杂凑派生函数Hash_df,返回长度为number_of_bits_to_return的比特串
+func (h Hash_Function) Hash_df(input_string []byte, number_of_bits_to_return int) []byte {
+	number_of_bits_to_return_bytes := tools.Int2Bytes(number_of_bits_to_return, 4)
+	temp := make([]byte, 0, number_of_bits_to_return/8+h.New().Size())
+	for counter := 1; len(temp)*8 < number_of_bits_to_return; counter++ {
+		temp = append(temp, h.sum(tools.Int2Bytes(counter, 1), number_of_bits_to_return_bytes, input_string)...)
+	}
+	return temp[:number_of_bits_to_return/8]
+}
+
+// dst=(dst+src) mod 2^(8*len(dst)),src按大端序右对齐
+func add_mod(dst []byte, src []byte) {
+	carry := 0
+	for i, j := len(dst)-1, len(src)-1; i >= 0; i, j = i-1, j-1 {
+		sum := int(dst[i]) + carry
+		if j >= 0 {
+			sum += int(src[j])
+		}
+		dst[i] = byte(sum)
+		carry = sum >> 8
+	}
+}
+
+// 初始化算法,返回V与C
+func (h Hash_Function) instantiate(entropy_input []byte, nonce []byte, personalization_string []byte) ([]byte, []byte) {
+	V := h.Hash_df(slices.Concat(entropy_input, nonce, personalization_string), h.Seedlen)
+	C := h.Hash_df(slices.Concat([]byte{0x00}, V), h.Seedlen)
+	return V, C
+}
+
+// 重播种算法,返回新的V与C。种子材料的拼接顺序由Reseed_Entropy_First决定
+func (h Hash_Function) reseed(V []byte, entropy_input []byte, addition_input []byte) ([]byte, []byte) {
+	if h.Reseed_Entropy_First {
+		V = h.Hash_df(slices.Concat([]byte{0x01}, entropy_input, V, addition_input), h.Seedlen)
+	} else {
+		V = h.Hash_df(slices.Concat([]byte{0x01}, V, entropy_input, addition_input), h.Seedlen)
+	}
+	C := h.Hash_df(slices.Concat([]byte{0x00}, V), h.Seedlen)
+	return V, C
+}
+
+// 输出算法,返回随机比特串与新的V
+func (h Hash_Function) generate(V []byte, C []byte, reseed_counter int, requested_number_of_bits int, addition_input []byte) ([]byte, []byte) {
+	V = slices.Clone(V)
+	if len(addition_input) != 0 {
+		add_mod(V, h.sum([]byte{0x02}, V, addition_input))
+	}
+	//Hashgen
+	data := slices.Clone(V)
+	returned_bits := make([]byte, 0, requested_number_of_bits/8+h.New().Size())
+	for len(returned_bits)*8 < requested_number_of_bits {
+		returned_bits = append(returned_bits, h.sum(data)...)
+		add_mod(data, []byte{0x01})
+	}
+	H := h.sum([]byte{0x03}, V)
+	add_mod(V, H)
+	add_mod(V, C)
+	add_mod(V, tools.Int2Bytes(reseed_counter, 8))
+	return returned_bits[:requested_number_of_bits/8], V
+}
+
+// 创建Hash_DRBG并初始化
+func New_Hash_DRBG(h Hash_Function, entropy_input []byte, nonce []byte, personalization_string []byte) *Hash_DRBG {
+	V, C := h.instantiate(entropy_input, nonce, personalization_string)
+	return &Hash_DRBG{Hash: h, V: V, C: C, Reseed_Counter: 1}
+}
+
+// 以给定熵输入重播种
+func (hash_drbg *Hash_DRBG) Reseed(entropy_input []byte, addition_input []byte) {
+	hash_drbg.V, hash_drbg.C = hash_drbg.Hash.reseed(hash_drbg.V, entropy_input, addition_input)
+	hash_drbg.Reseed_Counter = 1
+}
+
+// 输出随机比特串
+func (hash_drbg *Hash_DRBG) Generate(requested_number_of_bits int, addition_input []byte) []byte {
+	returned_bits, V := hash_drbg.Hash.generate(hash_drbg.V, hash_drbg.C, hash_drbg.Reseed_Counter, requested_number_of_bits, addition_input)
+	hash_drbg.V = V
+	hash_drbg.Reseed_Counter++
+	return returned_bits
+}
+
+// 实现io.Reader,每次请求至多hash_drbg_max_request字节
+func (hash_drbg *Hash_DRBG) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		n += copy(p[n:], hash_drbg.Generate(min(len(p)-n, hash_drbg_max_request)*8, nil))
+	}
+	return n, nil
+}
+
+// 通用Hash_DRBG已知答案测试,使用NIST CAVP Hash_DRBG测试向量(SHA-256,无预测抗性,COUNT=0),比对第二次输出
+func Test_KnownAnswer_Hash() int {
+	entropy_input, _ := hex.DecodeString("a65ad0f345db4e0effe875c3a2e71f42c7129d620ff5c119a9ef55f05185e0fb")
+	nonce, _ := hex.DecodeString("8581f9317517276e06e9607ddbcbcc2e")
+	target, _ := hex.DecodeString("d3e160c35b99f340b2628264d1751060e0045da383ff57a57d73a673d2b8d80daaf6a6c35a91bb4579d73fd0c8fed111b0391306828adfed528f018121b3febdc343e797b87dbb63db1333ded9d1ece177cfa6b71fe8ab1da46624ed6415e51ccde2c7ca86e283990eeaeb91120415528b2295910281b02dd431f4c9f70427df")
+	hash_drbg := New_Hash_DRBG(SHA256_Hash, entropy_input, nonce, nil)
+	hash_drbg.Generate(1024, nil)
+	if slices.Equal(hash_drbg.Generate(1024, nil), target) {
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,142 @@
+package drbg
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jellygdh/drbg_sm3/sm3"
+)
+
+// HMAC_DRBG内部状态结构体
+type HMAC_Working_State struct {
+	V                []byte //比特串,长度为outlen,在每次调用DRBG时更新值
+	K                []byte //HMAC密钥,长度为outlen,在每次调用DRBG时更新值
+	Reseed_Counter   int    //重播种计数器值
+	Last_Reseed_Time int64  //重播种时间值(单位:秒)
+}
+
+// HMAC_DRBG状态更新函数
+func (working_state *HMAC_Working_State) HMAC_DRBG_Update(provided_data []byte) {
+	working_state.K = sm3.HMAC(working_state.K, working_state.V, []byte{0x00}, provided_data)
+	working_state.V = sm3.HMAC(working_state.K, working_state.V)
+	if len(provided_data) == 0 {
+		return
+	}
+	working_state.K = sm3.HMAC(working_state.K, working_state.V, []byte{0x01}, provided_data)
+	working_state.V = sm3.HMAC(working_state.K, working_state.V)
+}
+
+// 由熵输入、nonce与个性化字符串建立初始状态
+func (working_state *HMAC_Working_State) hmac_drbg_instantiate(entropy_input []byte, nonce []byte, personalization_string []byte) {
+	working_state.K = make([]byte, outlen/8)
+	working_state.V = make([]byte, outlen/8)
+	for i := range working_state.V {
+		working_state.V[i] = 0x01
+	}
+	working_state.HMAC_DRBG_Update(slices.Concat(entropy_input, nonce, personalization_string))
+	working_state.Reseed_Counter = 1
+	working_state.Last_Reseed_Time = time.Now().Unix()
+}
+
+// 初始化函数
+func (working_state *HMAC_Working_State) HMAC_DRBG_Instantiate(personalization_string string) {
+	if Test_KnownAnswer_HMAC() == -1 {
+		fmt.Println("HMAC_DRBG已知答案测试未通过!")
+	}
+	nonce, err := Get_Nonce()
+	if err != nil {
+		fmt.Println("HMAC_DRBG_Instantiate error:", err)
+		return
+	}
+	min_entropy = min_entropy_input_length
+	working_state.hmac_drbg_instantiate(working_state.get_entropy(), nonce, []byte(personalization_string))
+}
+
+// 从熵池获取熵输入,获取失败时重试
+func (working_state *HMAC_Working_State) get_entropy() []byte {
+	i, entropy_input := Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	for i == -1 {
+		i, entropy_input = Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	}
+	return entropy_input
+}
+
+// 重播种函数
+func (working_state *HMAC_Working_State) HMAC_DRBG_Reseed(entropy_input []byte, addition_input []byte) {
+	working_state.HMAC_DRBG_Update(slices.Concat(entropy_input, addition_input))
+	working_state.Reseed_Counter = 1
+	working_state.Last_Reseed_Time = time.Now().Unix()
+}
+
+// 输出函数,达到重播种计数器阈值或时间阈值时先从熵池重播种;已卸载时返回nil
+func (working_state *HMAC_Working_State) HMAC_DRBG_Generate(requested_number_of_bits int, addition_input string) []byte {
+	if working_state.K == nil {
+		fmt.Println("HMAC_DRBG未初始化!")
+		return nil
+	}
+	addition_input_bytes := []byte(addition_input)
+	if working_state.Reseed_Counter > reseed_interval_in_counter || time.Now().Unix()-working_state.Last_Reseed_Time > reseed_interval_in_time {
+		working_state.HMAC_DRBG_Reseed(working_state.get_entropy(), addition_input_bytes)
+		addition_input_bytes = nil
+	}
+	if len(addition_input_bytes) != 0 {
+		working_state.HMAC_DRBG_Update(addition_input_bytes)
+	}
+	temp := make([]byte, 0, requested_number_of_bits/8+outlen/8)
+	for len(temp)*8 < requested_number_of_bits {
+		working_state.V = sm3.HMAC(working_state.K, working_state.V)
+		temp = append(temp, working_state.V...)
+	}
+	working_state.HMAC_DRBG_Update(addition_input_bytes)
+	working_state.Reseed_Counter++
+	return temp[:requested_number_of_bits/8]
+}
+
+// 卸载函数,清除内部状态
+func (working_state *HMAC_Working_State) HMAC_DRBG_Uninstantiate() {
+	clear(working_state.K)
+	clear(working_state.V)
+	working_state.K = nil
+	working_state.V = nil
+	working_state.Reseed_Counter = 0
+	working_state.Last_Reseed_Time = 0
+}
+
+// HMAC_DRBG已知答案测试,与Test_KnownAnswer使用相同的熵输入与nonce,比对第二次输出
+func Test_KnownAnswer_HMAC() int {
+	nonce, _ := hex.DecodeString("012345670123456700000001331051e42be3c2139b4077728785ff2553d1d7ffc7c98377875581837ee6a99501bd28a12c491ea656e5666286fdabc56bb05d811596e9667b165367c7d2e4c8")
+	entropy_input, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f30313233343536")
+	target, _ := hex.DecodeString("292f65cb4e5d3662884d56a6a6ba6075dc6bef3685cd5d2e50e0e65e7e7d1236")
+	working_state := new(HMAC_Working_State)
+	working_state.hmac_drbg_instantiate(entropy_input, nonce, nil)
+	working_state.HMAC_DRBG_Generate(256, "")
+	result := working_state.HMAC_DRBG_Generate(256, "")
+	if slices.Equal(result, target) {
+		return 0
+	}
+	return -1
+}
+
+// 初始化HMAC_DRBG
+func Init_HMAC_DRBG_SM3(Mode int, personalization_string string) *HMAC_Working_State {
+	go Select_Mode(Mode)
+	working_state := new(HMAC_Working_State)
+	working_state.HMAC_DRBG_Instantiate(personalization_string)
+	return working_state
+}
+
+// 实现io.Reader,按outlen比特分组调用输出函数
+func (working_state *HMAC_Working_State) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		random_bytes := working_state.HMAC_DRBG_Generate(outlen, "")
+		if len(random_bytes) == 0 {
+			return n, errors.New("HMAC_DRBG未初始化")
+		}
+		n += copy(p[n:], random_bytes)
+	}
+	return n, nil
+}
@@ -0,0 +1,200 @@
+package drbg
+
+import (
+	crypto_rand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/host"
+)
+
+const (
+	nonce_reserve_block     = 1 << 16 //每次持久化预留的计数器区间长度
+	nonce_host_id_length    = 8       //主机标识长度(单位:字节)
+	nonce_entropy_length    = 16      //新鲜熵长度(单位:字节)
+	nonce_length            = 8 + 8 + 4 + nonce_host_id_length + nonce_entropy_length
+	nonce_counter_file_mode = 0600                  //计数器文件权限
+	nonce_max_attempts      = 3                     //Get_Nonce的最大尝试次数
+	nonce_retry_delay       = 10 * time.Millisecond //Get_Nonce重试的基础等待时间,第k次失败后等待k倍
+)
+
+// nonce生成器。nonce=计数器(8字节)||纳秒时间戳(8字节)||进程号(4字节)||主机标识(8字节)||新鲜熵(16字节)。
+//
+// 唯一性论证:
+//  1. 同一生成器内,计数器在互斥锁保护下严格递增,任意两个nonce的计数器字段不同;
+//  2. 指定计数器文件时,计数器区间在使用前先写入文件(预留上界),重启后从已预留的上界继续,
+//     即使进程崩溃也只会跳过未用完的区间,不会重复使用;
+//  3. 同一主机上同时运行的进程进程号互不相同;进程号复用发生在不同时刻,由时间戳区分;
+//  4. 不同主机由主机标识区分;
+//  5. 以上条件因时钟回拨、文件丢失或主机标识冲突而失效时,128比特新鲜熵使碰撞概率不超过2^-128量级(按生日界)。
+type Nonce_Generator struct {
+	Counter_File string //持久化计数器文件路径,为空时计数器仅在进程内单调
+	mutex        sync.Mutex
+	counter      uint64 //下一个计数器值
+	reserved     uint64 //已持久化的计数器上界(不含)
+	host_id      []byte //主机标识
+}
+
+var default_nonce_generator = new(Nonce_Generator) //Get_Nonce使用的生成器
+
+// 设置Get_Nonce使用的持久化计数器文件
+func Set_Nonce_Counter_File(path string) {
+	default_nonce_generator.mutex.Lock()
+	defer default_nonce_generator.mutex.Unlock()
+	default_nonce_generator.Counter_File = path
+	default_nonce_generator.reserved = 0
+}
+
+// 主机标识:SM3(主机ID||主机名)的前nonce_host_id_length字节
+func nonce_host_id() []byte {
+	host_id, _ := host.HostID()
+	hostname, _ := os.Hostname()
+	return SM3_Hash.sum([]byte(host_id), []byte{0x00}, []byte(hostname))[:nonce_host_id_length]
+}
+
+// 读取以十进制文本保存的计数器文件,文件不存在时返回0
+func read_counter_file(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// 预留下一段计数器区间,调用者须持有互斥锁
+func (generator *Nonce_Generator) reserve() error {
+	if generator.Counter_File != "" {
+		stored, err := read_counter_file(generator.Counter_File)
+		if err != nil {
+			return fmt.Errorf("Nonce_Generator error: %w", err)
+		}
+		generator.counter = max(generator.counter, stored)
+	}
+	if generator.counter > ^uint64(0)-nonce_reserve_block {
+		return errors.New("Nonce_Generator error: counter exhausted")
+	}
+	reserved := generator.counter + nonce_reserve_block
+	if generator.Counter_File != "" {
+		if err := write_file_atomic(generator.Counter_File, []byte(strconv.FormatUint(reserved, 10)+"\n"), nonce_counter_file_mode); err != nil {
+			return fmt.Errorf("Nonce_Generator error: %w", err)
+		}
+	}
+	generator.reserved = reserved
+	return nil
+}
+
+// 生成nonce
+func (generator *Nonce_Generator) Next() ([]byte, error) {
+	generator.mutex.Lock()
+	if generator.counter >= generator.reserved {
+		if err := generator.reserve(); err != nil {
+			generator.mutex.Unlock()
+			return nil, err
+		}
+	}
+	if generator.host_id == nil {
+		generator.host_id = nonce_host_id()
+	}
+	counter := generator.counter
+	generator.counter++
+	host_id := generator.host_id
+	generator.mutex.Unlock()
+
+	nonce := make([]byte, 0, nonce_length)
+	nonce = binary.BigEndian.AppendUint64(nonce, counter)
+	nonce = binary.BigEndian.AppendUint64(nonce, uint64(time.Now().UnixNano()))
+	nonce = binary.BigEndian.AppendUint32(nonce, uint32(os.Getpid()))
+	nonce = append(nonce, host_id...)
+	entropy := make([]byte, nonce_entropy_length)
+	if _, err := crypto_rand.Read(entropy); err != nil {
+		return nil, fmt.Errorf("Nonce_Generator error: %w", err)
+	}
+	return append(nonce, entropy...), nil
+}
+
+// nonce唯一性自检:goroutines个协程共生成n个nonce,检查去掉新鲜熵后的前缀(计数器||时间戳||进程号||主机标识)无重复,
+// 即唯一性不依赖新鲜熵;以同一计数器文件先后创建三个生成器模拟重启,检查计数器不回退;
+// 计数器文件不可写时应返回错误而非nonce
+func Test_Nonce_Uniqueness(n int, goroutines int) int {
+	generator := new(Nonce_Generator)
+	shards := make([]map[string]struct{}, goroutines)
+	var wait_group sync.WaitGroup
+	failed := false
+	var failed_mutex sync.Mutex
+	for g := 0; g < goroutines; g++ {
+		count := n / goroutines
+		if g < n%goroutines {
+			count++
+		}
+		shards[g] = make(map[string]struct{}, count)
+		wait_group.Add(1)
+		go func(shard map[string]struct{}, count int) {
+			defer wait_group.Done()
+			for i := 0; i < count; i++ {
+				nonce, err := generator.Next()
+				if err != nil {
+					failed_mutex.Lock()
+					failed = true
+					failed_mutex.Unlock()
+					return
+				}
+				shard[string(nonce[:nonce_length-nonce_entropy_length])] = struct{}{}
+			}
+		}(shards[g], count)
+	}
+	wait_group.Wait()
+	if failed {
+		return -1
+	}
+	seen := make(map[string]struct{}, n)
+	for _, shard := range shards {
+		for nonce := range shard {
+			seen[nonce] = struct{}{}
+		}
+	}
+	if len(seen) != n {
+		fmt.Println("nonce前缀重复:", n-len(seen))
+		return -1
+	}
+
+	dir, err := os.MkdirTemp("", "nonce")
+	if err != nil {
+		return -1
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nonce_counter")
+	last := uint64(0)
+	for restart := 0; restart < 3; restart++ {
+		generator := &Nonce_Generator{Counter_File: path}
+		for i := 0; i < 10; i++ {
+			nonce, err := generator.Next()
+			if err != nil {
+				return -1
+			}
+			counter := binary.BigEndian.Uint64(nonce)
+			if (restart > 0 || i > 0) && counter <= last {
+				return -1
+			}
+			last = counter
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != nonce_counter_file_mode {
+		return -1
+	}
+	unwritable := &Nonce_Generator{Counter_File: filepath.Join(dir, "missing", "nonce_counter")}
+	if nonce, err := unwritable.Next(); err == nil || nonce != nil {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package drbg
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// 熵输入、nonce与时间的提供者。默认从熵池获取;包内自检可通过new_test_working_state注入记录的输入,
+// 使初始化、输出以及自动重播种在内的完整输出序列可复现。
+// 无法提供熵输入或nonce时返回nil,DRBG随即进入错误状态,不会以空输入继续初始化或重播种
+type Entropy_Provider interface {
+	Entropy_Input() []byte //获取熵输入
+	Nonce() []byte         //获取nonce
+	Now() int64            //当前时间(单位:秒),用于重播种时间阈值判断
+}
+
+// 默认提供者:熵池、Get_Nonce与系统时间
+type pool_provider struct{}
+
+func (pool_provider) Entropy_Input() []byte {
+	min_entropy = min_entropy_input_length
+	i, entropy_input := Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	for i == -1 {
+		i, entropy_input = Get_Entropy(min_entropy, min_entropy_input_length, max_entropy_input_length)
+	}
+	return entropy_input
+}
+
+func (pool_provider) Nonce() []byte {
+	nonce, err := Get_Nonce()
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	return nonce
+}
+
+func (pool_provider) Now() int64 {
+	return time.Now().Unix()
+}
+
+// 回放记录的输入,按顺序依次返回;时间用尽后保持最后一个值
+type Recorded_Provider struct {
+	Entropy_Inputs [][]byte //熵输入序列
+	Nonces         [][]byte //nonce序列
+	Times          []int64  //时间序列(单位:秒)
+	entropy_index  int
+	nonce_index    int
+	time_index     int
+}
+
+// 熵输入用尽时返回nil
+func (provider *Recorded_Provider) Entropy_Input() []byte {
+	if provider.entropy_index >= len(provider.Entropy_Inputs) {
+		fmt.Println("Recorded_Provider error: entropy inputs exhausted")
+		return nil
+	}
+	provider.entropy_index++
+	return provider.Entropy_Inputs[provider.entropy_index-1]
+}
+
+// nonce用尽时返回nil
+func (provider *Recorded_Provider) Nonce() []byte {
+	if provider.nonce_index >= len(provider.Nonces) {
+		fmt.Println("Recorded_Provider error: nonces exhausted")
+		return nil
+	}
+	provider.nonce_index++
+	return provider.Nonces[provider.nonce_index-1]
+}
+
+func (provider *Recorded_Provider) Now() int64 {
+	if len(provider.Times) == 0 {
+		return 0
+	}
+	if provider.time_index < len(provider.Times) {
+		provider.time_index++
+	}
+	return provider.Times[provider.time_index-1]
+}
+
+// 已回放的熵输入个数
+func (provider *Recorded_Provider) Entropy_Consumed() int {
+	return provider.entropy_index
+}
+
+// 记录被包装提供者的全部输出,之后可通过Replay回放
+type Recording_Provider struct {
+	Provider Entropy_Provider  //被记录的提供者
+	Record   Recorded_Provider //记录结果
+}
+
+func (provider *Recording_Provider) Entropy_Input() []byte {
+	entropy_input := slices.Clone(provider.Provider.Entropy_Input())
+	provider.Record.Entropy_Inputs = append(provider.Record.Entropy_Inputs, entropy_input)
+	return entropy_input
+}
+
+func (provider *Recording_Provider) Nonce() []byte {
+	nonce := slices.Clone(provider.Provider.Nonce())
+	provider.Record.Nonces = append(provider.Record.Nonces, nonce)
+	return nonce
+}
+
+func (provider *Recording_Provider) Now() int64 {
+	now := provider.Provider.Now()
+	provider.Record.Times = append(provider.Record.Times, now)
+	return now
+}
+
+// 从头回放已记录的输入
+func (provider *Recording_Provider) Replay() *Recorded_Provider {
+	return &Recorded_Provider{
+		Entropy_Inputs: provider.Record.Entropy_Inputs,
+		Nonces:         provider.Record.Nonces,
+		Times:          provider.Record.Times,
+	}
+}
+
+// 仅供包内自检使用:返回以provider代替熵池、Get_Nonce与系统时间的未初始化状态,随后调用SM3_DRBG_Instantiate
+func new_test_working_state(provider Entropy_Provider) *Working_State {
+	return &Working_State{entropy_provider: provider}
+}
+
+// 当前使用的提供者,未注入时使用熵池
+func (working_state *Working_State) provider() Entropy_Provider {
+	if working_state.entropy_provider == nil {
+		return pool_provider{}
+	}
+	return working_state.entropy_provider
+}
+
+// 可复现性自检:以同一组记录输入两次运行完整生命周期(含计数器与时间触发的自动重播种),比对输出序列;
+// 并检查记录的熵输入用尽后,自动重播种使DRBG进入错误状态而不是以空熵输入继续输出
+func Test_Reproducible() int {
+	new_provider := func() *Recorded_Provider {
+		provider := &Recorded_Provider{Times: []int64{0}}
+		for i := 0; i < 64; i++ {
+			provider.Entropy_Inputs = append(provider.Entropy_Inputs, SM3_Hash.sum([]byte("entropy"), []byte{byte(i)}))
+			provider.Nonces = append(provider.Nonces, SM3_Hash.sum([]byte("nonce"), []byte{byte(i)})[:16])
+		}
+		return provider
+	}
+	run := func(provider *Recorded_Provider) []byte {
+		working_state := new_test_working_state(provider)
+		working_state.SM3_DRBG_Instantiate("reproducible")
+		output := make([]byte, 0, 2*(reseed_interval_in_counter+1)*outlen/8)
+		for i := 0; i < 2*(reseed_interval_in_counter+1); i++ {
+			if i == reseed_interval_in_counter/2 {
+				provider.Times = append(provider.Times, reseed_interval_in_time+1)
+			}
+			output = append(output, working_state.SM3_DRBG_Generate(outlen, "")...)
+		}
+		return output
+	}
+	first_provider, second_provider := new_provider(), new_provider()
+	first, second := run(first_provider), run(second_provider)
+	if len(first) == 0 || !slices.Equal(first, second) || first_provider.Entropy_Consumed() < 3 {
+		return -1
+	}
+	exhausted := &Recorded_Provider{
+		Entropy_Inputs: [][]byte{SM3_Hash.sum([]byte("entropy"))},
+		Nonces:         [][]byte{SM3_Hash.sum([]byte("nonce"))[:16]},
+		Times:          []int64{0},
+	}
+	working_state := new_test_working_state(exhausted)
+	working_state.SM3_DRBG_Instantiate("exhausted")
+	if working_state.Error_State {
+		return -1
+	}
+	exhausted.Times = append(exhausted.Times, reseed_interval_in_time+1)
+	if working_state.SM3_DRBG_Generate(outlen, "") != nil || !working_state.Error_State {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package drbg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	seed_file_length = 64   //种子文件长度(单位:字节)
+	seed_file_mode   = 0600 //种子文件权限
+)
+
+// 以临时文件加重命名的方式原子地写入文件,写入前设置权限并同步到磁盘
+func write_file_atomic(path string, content []byte, mode os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// 读取种子文件并立即删除,保证同一种子不会被使用两次;文件不存在时返回nil。
+// 权限不为seed_file_mode时视为可能已泄露,删除但不使用
+func load_seed_file(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm() != seed_file_mode {
+		return nil, fmt.Errorf("insecure seed file permissions %v", info.Mode().Perm())
+	}
+	return seed, nil
+}
+
+// 以DRBG输出覆盖种子文件,在初始化、每次重播种后调用,关闭前应再调用一次
+func (working_state *Working_State) Save_Seed_File() int {
+	if working_state.Seed_File == "" || working_state.V == nil {
+		return 0
+	}
+	seed := working_state.sm3_drbg_generate(seed_file_length*8, "")
+	if err := write_file_atomic(working_state.Seed_File, seed, seed_file_mode); err != nil {
+		fmt.Println("Save_Seed_File error:", err)
+		return -1
+	}
+	return 0
+}
+
+// 初始化并使用种子文件:启动时将种子文件内容作为附加输入混入初始化,随即以新种子覆盖
+func Init_DRBG_SM3_Seed_File(Mode int, personalization_string string, seed_file string) *Working_State {
+	go Select_Mode(Mode)
+	working_state := &Working_State{Seed_File: seed_file}
+	working_state.SM3_DRBG_Instantiate(personalization_string)
+	return working_state
+}
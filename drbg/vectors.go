@@ -0,0 +1,286 @@
+package drbg
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed vectors/sm3_hash_drbg.rsp
+var sm3_vectors string //SM3 Hash_DRBG回归测试向量(CAVP .rsp格式),由vectors/sm3_hash_drbg.py独立生成
+
+// Hash_DRBG测试向量
+type DRBG_Vector struct {
+	Hash                    string   //杂凑函数名称
+	Prediction_Resistance   bool     //是否具备预测抗性
+	Count                   int      //向量编号
+	Entropy_Input           []byte   //初始化的熵输入
+	Nonce                   []byte   //初始化的nonce
+	Personalization_String  []byte   //个性化字符串
+	Reseed                  bool     //是否在初始化后重播种
+	Entropy_Input_Reseed    []byte   //重播种的熵输入
+	Additional_Input_Reseed []byte   //重播种的附加输入
+	Additional_Input        [][]byte //各次输出的附加输入
+	Entropy_Input_PR        [][]byte //具备预测抗性时各次输出前重播种的熵输入
+	Returned_Bits           []byte   //最后一次输出的期望结果
+}
+
+// 测试向量运行结果
+type Vector_Report struct {
+	Total    int      //向量总数
+	Passed   int      //通过数
+	Skipped  int      //因杂凑函数不支持而跳过的数量
+	Failures []string //未通过的向量
+}
+
+// 按测试向量驱动Hash_DRBG,返回最后一次输出
+func (vector DRBG_Vector) Run(h Hash_Function) []byte {
+	hash_drbg := New_Hash_DRBG(h, vector.Entropy_Input, vector.Nonce, vector.Personalization_String)
+	if vector.Reseed {
+		hash_drbg.Reseed(vector.Entropy_Input_Reseed, vector.Additional_Input_Reseed)
+	}
+	var returned_bits []byte
+	for i, addition_input := range vector.Additional_Input {
+		if vector.Prediction_Resistance {
+			hash_drbg.Reseed(vector.Entropy_Input_PR[i], addition_input)
+			addition_input = nil
+		}
+		returned_bits = hash_drbg.Generate(len(vector.Returned_Bits)*8, addition_input)
+	}
+	return returned_bits
+}
+
+// 运行一组测试向量
+func Run_Vectors(vectors []DRBG_Vector) Vector_Report {
+	var report Vector_Report
+	for _, vector := range vectors {
+		report.Total++
+		h, ok := Find_Hash_Function(vector.Hash)
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		if vector.Prediction_Resistance && len(vector.Entropy_Input_PR) != len(vector.Additional_Input) {
+			report.Failures = append(report.Failures, fmt.Sprintf("%s COUNT=%d: 预测抗性熵输入个数不匹配", vector.Hash, vector.Count))
+			continue
+		}
+		if result := vector.Run(h); !slices.Equal(result, vector.Returned_Bits) {
+			report.Failures = append(report.Failures, fmt.Sprintf("%s PR=%v COUNT=%d: 期望%x,实际%x", vector.Hash, vector.Prediction_Resistance, vector.Count, vector.Returned_Bits, result))
+			continue
+		}
+		report.Passed++
+	}
+	return report
+}
+
+// 解析CAVP Hash_DRBG.rsp格式的测试向量
+func Parse_RSP(reader io.Reader) ([]DRBG_Vector, error) {
+	var vectors []DRBG_Vector
+	var vector *DRBG_Vector
+	hash_name := ""
+	prediction_resistance := false
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1<<16), 1<<24)
+	for line_number := 1; scanner.Scan(); line_number++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		//跳过注释、空行以及中间状态(以**开头或缩进的V、C)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "**") || raw[0] == ' ' || raw[0] == '\t' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			key, value, found := strings.Cut(section, "=")
+			if !found {
+				hash_name = section
+			} else if strings.TrimSpace(key) == "PredictionResistance" {
+				prediction_resistance = strings.EqualFold(strings.TrimSpace(value), "True")
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("Parse_RSP error: line %d: %q", line_number, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key == "COUNT" {
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("Parse_RSP error: line %d: %w", line_number, err)
+			}
+			vectors = append(vectors, DRBG_Vector{Hash: hash_name, Prediction_Resistance: prediction_resistance, Count: count})
+			vector = &vectors[len(vectors)-1]
+			continue
+		}
+		if vector == nil {
+			return nil, fmt.Errorf("Parse_RSP error: line %d: %s before COUNT", line_number, key)
+		}
+		data, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("Parse_RSP error: line %d: %w", line_number, err)
+		}
+		switch key {
+		case "EntropyInput":
+			vector.Entropy_Input = data
+		case "Nonce":
+			vector.Nonce = data
+		case "PersonalizationString":
+			vector.Personalization_String = data
+		case "EntropyInputReseed":
+			vector.Reseed = true
+			vector.Entropy_Input_Reseed = data
+		case "AdditionalInputReseed":
+			vector.Additional_Input_Reseed = data
+		case "AdditionalInput":
+			vector.Additional_Input = append(vector.Additional_Input, data)
+		case "EntropyInputPR":
+			vector.Entropy_Input_PR = append(vector.Entropy_Input_PR, data)
+		case "ReturnedBits":
+			vector.Returned_Bits = data
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// ACVP测试组
+type acvp_group struct {
+	Mode            string      `json:"mode"`
+	Pred_Resistance bool        `json:"predResistance"`
+	Tests           []acvp_test `json:"tests"`
+}
+
+// ACVP测试用例
+type acvp_test struct {
+	Tc_Id         int    `json:"tcId"`
+	Entropy_Input string `json:"entropyInput"`
+	Nonce         string `json:"nonce"`
+	Perso_String  string `json:"persoString"`
+	Other_Input   []struct {
+		Intended_Use     string `json:"intendedUse"`
+		Additional_Input string `json:"additionalInput"`
+		Entropy_Input    string `json:"entropyInput"`
+	} `json:"otherInput"`
+	Returned_Bits string `json:"returnedBits"`
+}
+
+// ACVP向量集
+type acvp_vector_set struct {
+	Algorithm   string       `json:"algorithm"`
+	Test_Groups []acvp_group `json:"testGroups"`
+}
+
+// 解析ACVP JSON格式(含期望结果,如internalProjection.json)的hashDRBG测试组,兼容带acvVersion头部的数组格式
+func Parse_ACVP(reader io.Reader) ([]DRBG_Vector, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var sets []acvp_vector_set
+	if err := json.Unmarshal(content, &sets); err != nil {
+		var set acvp_vector_set
+		if err := json.Unmarshal(content, &set); err != nil {
+			return nil, fmt.Errorf("Parse_ACVP error: %w", err)
+		}
+		sets = []acvp_vector_set{set}
+	}
+	var vectors []DRBG_Vector
+	decode := func(s string) []byte {
+		data, e := hex.DecodeString(s)
+		if e != nil && err == nil {
+			err = e
+		}
+		return data
+	}
+	for _, set := range sets {
+		if set.Algorithm != "" && !strings.EqualFold(set.Algorithm, "hashDRBG") {
+			continue
+		}
+		for _, group := range set.Test_Groups {
+			for _, test := range group.Tests {
+				if test.Returned_Bits == "" {
+					continue
+				}
+				vector := DRBG_Vector{
+					Hash:                   group.Mode,
+					Prediction_Resistance:  group.Pred_Resistance,
+					Count:                  test.Tc_Id,
+					Entropy_Input:          decode(test.Entropy_Input),
+					Nonce:                  decode(test.Nonce),
+					Personalization_String: decode(test.Perso_String),
+					Returned_Bits:          decode(test.Returned_Bits),
+				}
+				for _, other := range test.Other_Input {
+					if strings.EqualFold(other.Intended_Use, "reSeed") {
+						vector.Reseed = true
+						vector.Entropy_Input_Reseed = decode(other.Entropy_Input)
+						vector.Additional_Input_Reseed = decode(other.Additional_Input)
+						continue
+					}
+					vector.Additional_Input = append(vector.Additional_Input, decode(other.Additional_Input))
+					if group.Pred_Resistance {
+						vector.Entropy_Input_PR = append(vector.Entropy_Input_PR, decode(other.Entropy_Input))
+					}
+				}
+				vectors = append(vectors, vector)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Parse_ACVP error: %w", err)
+	}
+	return vectors, nil
+}
+
+// 运行测试向量文件,按扩展名区分.rsp与.json格式
+func Run_Vector_File(path string) (Vector_Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Vector_Report{}, err
+	}
+	defer file.Close()
+	var vectors []DRBG_Vector
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rsp":
+		vectors, err = Parse_RSP(file)
+	case ".json":
+		vectors, err = Parse_ACVP(file)
+	default:
+		err = errors.New("Run_Vector_File error: unsupported file type")
+	}
+	if err != nil {
+		return Vector_Report{}, err
+	}
+	return Run_Vectors(vectors), nil
+}
+
+// 运行内置的SM3 Hash_DRBG回归测试向量。尚无GM/T 0105官方向量,期望值来自独立实现而非标准
+func Test_SM3_Vectors() Vector_Report {
+	vectors, err := Parse_RSP(strings.NewReader(sm3_vectors))
+	if err != nil {
+		return Vector_Report{Failures: []string{err.Error()}}
+	}
+	return Run_Vectors(vectors)
+}
+
+// 测试向量运行结果->文本
+func (report Vector_Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "向量总数: %d, 通过: %d, 跳过: %d, 未通过: %d\n", report.Total, report.Passed, report.Skipped, len(report.Failures))
+	for _, failure := range report.Failures {
+		b.WriteString(failure + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+#!/usr/bin/env python3
+"""Generate SM3 Hash_DRBG regression vectors in CAVP Hash_DRBG.rsp layout.
+
+This is an implementation of Hash_DRBG written directly from GM/T 0105-2021
+and SP 800-90A, independent of the Go code in this repository. SM3 comes from
+OpenSSL through hashlib. Reseeding uses the GM/T 0105 order
+0x01 || entropy_input || V || additional_input.
+
+Running with --check-sha256 first reproduces a NIST CAVP SHA-256 Hash_DRBG
+vector (SP 800-90A reseed order) to validate this implementation itself.
+
+Usage: python3 sm3_hash_drbg.py > sm3_hash_drbg.rsp
+"""
+import hashlib
+import sys
+
+SEEDLEN = 440
+
+
+def H(name, *parts):
+    h = hashlib.new(name)
+    for p in parts:
+        h.update(p)
+    return h.digest()
+
+
+def hash_df(name, data, bits):
+    out = b""
+    counter = 1
+    while len(out) * 8 < bits:
+        out += H(name, bytes([counter]), bits.to_bytes(4, "big"), data)
+        counter += 1
+    return out[: bits // 8]
+
+
+def add(*values):
+    total = sum(int.from_bytes(v, "big") if isinstance(v, bytes) else v for v in values)
+    return (total % (1 << SEEDLEN)).to_bytes(SEEDLEN // 8, "big")
+
+
+class HashDRBG:
+    def __init__(self, name, entropy, nonce, pers, gmt_order):
+        self.name, self.gmt_order = name, gmt_order
+        self.V = hash_df(name, entropy + nonce + pers, SEEDLEN)
+        self.C = hash_df(name, b"\x00" + self.V, SEEDLEN)
+        self.counter = 1
+
+    def reseed(self, entropy, addl):
+        material = entropy + self.V if self.gmt_order else self.V + entropy
+        self.V = hash_df(self.name, b"\x01" + material + addl, SEEDLEN)
+        self.C = hash_df(self.name, b"\x00" + self.V, SEEDLEN)
+        self.counter = 1
+
+    def generate(self, nbytes, addl):
+        if addl:
+            self.V = add(self.V, H(self.name, b"\x02" + self.V + addl))
+        data, out = self.V, b""
+        while len(out) < nbytes:
+            out += H(self.name, data)
+            data = add(data, 1)
+        self.V = add(self.V, H(self.name, b"\x03" + self.V), self.C, self.counter)
+        self.counter += 1
+        return out[:nbytes]
+
+
+def run(name, gmt_order, case):
+    drbg = HashDRBG(name, case["entropy"], case["nonce"], case["pers"], gmt_order)
+    if case.get("reseed"):
+        drbg.reseed(case["entropy_reseed"], case["addl_reseed"])
+    out = b""
+    for i, addl in enumerate(case["addl"]):
+        if case.get("pr"):
+            drbg.reseed(case["entropy_pr"][i], addl)
+            addl = b""
+        out = drbg.generate(128, addl)
+    return out
+
+
+def check_sha256():
+    case = {
+        "entropy": bytes.fromhex("a65ad0f345db4e0effe875c3a2e71f42c7129d620ff5c119a9ef55f05185e0fb"),
+        "nonce": bytes.fromhex("8581f9317517276e06e9607ddbcbcc2e"),
+        "pers": b"",
+        "addl": [b"", b""],
+    }
+    expected = "d3e160c35b99f340b2628264d1751060e0045da383ff57a57d73a673d2b8d80daaf6a6c35a91bb4579d73fd0c8fed111b0391306828adfed528f018121b3febdc343e797b87dbb63db1333ded9d1ece177cfa6b71fe8ab1da46624ed6415e51ccde2c7ca86e283990eeaeb91120415528b2295910281b02dd431f4c9f70427df"
+    assert run("sha256", False, case).hex() == expected, "CAVP SHA-256 vector mismatch"
+
+
+def main():
+    if "--check-sha256" in sys.argv:
+        check_sha256()
+    seq = [0]
+
+    def fill(n):
+        seq[0] += 1
+        out, i = b"", 0
+        while len(out) < n:
+            out += H("sm3", b"GM/T 0105 Hash_DRBG SM3 vector %d/%d" % (seq[0], i))
+            i += 1
+        return out[:n]
+
+    print("# SM3 Hash_DRBG regression vectors (GM/T 0105-2021), CAVP Hash_DRBG.rsp layout")
+    print("# No official GM/T 0105 SM3 vectors are published. These ReturnedBits were")
+    print("# produced by sm3_hash_drbg.py, an implementation written from the standard")
+    print("# independently of the Go code, using OpenSSL SM3. It reproduces the NIST CAVP")
+    print("# SHA-256 Hash_DRBG vectors when run with SP 800-90A reseed order.")
+    print("# Reseed order: 0x01||entropy_input||V||additional_input (GM/T 0105).")
+    print("# Each case instantiates, optionally reseeds, generates twice and compares")
+    print("# the second output.")
+    groups = [(False, False, False, False), (False, False, True, True), (False, True, True, True), (True, False, True, True)]
+    for pr, reseed, pers, addl in groups:
+        print()
+        print("[SM3]")
+        print("[PredictionResistance = %s]" % ("True" if pr else "False"))
+        print("[EntropyInputLen = 256]")
+        print("[NonceLen = 128]")
+        print("[PersonalizationStringLen = %d]" % (256 if pers else 0))
+        print("[AdditionalInputLen = %d]" % (256 if addl else 0))
+        print("[ReturnedBitsLen = 1024]")
+        print()
+        for count in range(3):
+            case = {"pr": pr, "reseed": reseed, "addl": [], "entropy_pr": []}
+            case["entropy"], case["nonce"] = fill(32), fill(16)
+            case["pers"] = fill(32) if pers else b""
+            print("COUNT = %d" % count)
+            print("EntropyInput = %s" % case["entropy"].hex())
+            print("Nonce = %s" % case["nonce"].hex())
+            print("PersonalizationString = %s" % case["pers"].hex())
+            if reseed:
+                case["entropy_reseed"] = fill(32)
+                case["addl_reseed"] = fill(32) if addl else b""
+                print("EntropyInputReseed = %s" % case["entropy_reseed"].hex())
+                print("AdditionalInputReseed = %s" % case["addl_reseed"].hex())
+            for _ in range(2):
+                a = fill(32) if addl else b""
+                case["addl"].append(a)
+                print("AdditionalInput = %s" % a.hex())
+                if pr:
+                    e = fill(32)
+                    case["entropy_pr"].append(e)
+                    print("EntropyInputPR = %s" % e.hex())
+            print("ReturnedBits = %s" % run("sm3", True, case).hex())
+            print()
+
+
+if __name__ == "__main__":
+    main()
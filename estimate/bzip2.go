@@ -0,0 +1,531 @@
+package estimate
+
+// bzip2压缩,用于SP 800-90B 5.1.11的压缩统计量。Go标准库只提供bzip2解压(compress/bzip2),
+// 此处按libbzip2 1.0.x的算法实现压缩:游程编码、循环BWT、MTF与RUNA/RUNB、多Huffman表
+// (按50个符号分组选择编码表并迭代4次,码长不超过17),输出与BZ2_bzBuffToBuffCompress一致。
+// 唯一的例外是周期性的块:相同循环移位之间的次序与libbzip2的排序算法有关,原始位置字段可能不同,压缩长度相同
+
+const (
+	bzip2_block_size      = 5  //块大小(单位:100k字节),与NIST参考工具调用BZ2_bzBuffToBuffCompress的参数一致
+	bzip2_group_size      = 50 //每个选择子覆盖的MTF符号数
+	bzip2_iterations      = 4  //编码表的迭代次数
+	bzip2_max_code_length = 17 //Huffman码长上限
+	bzip2_max_groups      = 6  //编码表数量上限
+	bzip2_run_a           = 0  //零游程编码符号RUNA
+	bzip2_run_b           = 1  //零游程编码符号RUNB
+)
+
+// bzip2使用的CRC32(多项式0x04C11DB7,高位在前)
+var bzip2_crc_table = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// 高位在前的比特输出
+type bit_writer struct {
+	out    []byte
+	buffer uint64
+	count  uint
+}
+
+// 写入v的低n位(n<=32)
+func (w *bit_writer) write(n uint, v uint32) {
+	w.buffer = w.buffer<<n | uint64(v)&(1<<n-1)
+	w.count += n
+	for w.count >= 8 {
+		w.count -= 8
+		w.out = append(w.out, byte(w.buffer>>w.count))
+	}
+}
+
+// 以0补齐最后一个字节
+func (w *bit_writer) flush() {
+	if w.count > 0 {
+		w.out = append(w.out, byte(w.buffer<<(8-w.count)))
+		w.count = 0
+	}
+}
+
+// bzip2压缩器,工作区可在多次压缩间复用
+type bzip2_encoder struct {
+	bits      bit_writer
+	block     []byte    //游程编码后的块
+	in_use    [256]bool //块中出现过的字节
+	crc       uint32    //块CRC
+	combined  uint32    //流CRC
+	run_ch    int       //当前游程的字节,256表示无
+	run_len   int       //当前游程的长度
+	sa        []int     //循环BWT的排序结果
+	rank      []int
+	next_rank []int
+	second    []int
+	counts    []int
+	mtf       []uint16 //MTF与零游程编码后的符号
+}
+
+// 压缩data,返回bzip2格式的数据(块大小为bzip2_block_size)
+func (encoder *bzip2_encoder) compress(data []byte) []byte {
+	max_block := bzip2_block_size*100000 - 19
+	encoder.bits = bit_writer{out: encoder.bits.out[:0]}
+	encoder.block = encoder.block[:0]
+	encoder.in_use = [256]bool{}
+	encoder.crc = 0xffffffff
+	encoder.combined = 0
+	encoder.run_ch, encoder.run_len = 256, 0
+	for _, b := range []byte{'B', 'Z', 'h', '0' + bzip2_block_size} {
+		encoder.bits.write(8, uint32(b))
+	}
+	for _, b := range data {
+		if len(encoder.block) >= max_block {
+			encoder.end_block()
+		}
+		if int(b) != encoder.run_ch || encoder.run_len == 255 {
+			encoder.add_run()
+			encoder.run_ch, encoder.run_len = int(b), 1
+		} else {
+			encoder.run_len++
+		}
+	}
+	encoder.add_run()
+	encoder.end_block()
+	for _, b := range []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90} {
+		encoder.bits.write(8, uint32(b))
+	}
+	encoder.bits.write(32, encoder.combined)
+	encoder.bits.flush()
+	return encoder.bits.out
+}
+
+// 当前游程写入块:长度1-3时原样写入,长度4-255时写入4个字节及长度减4
+func (encoder *bzip2_encoder) add_run() {
+	if encoder.run_ch == 256 {
+		return
+	}
+	ch := byte(encoder.run_ch)
+	for i := 0; i < encoder.run_len; i++ {
+		encoder.crc = encoder.crc<<8 ^ bzip2_crc_table[byte(encoder.crc>>24)^ch]
+	}
+	encoder.in_use[ch] = true
+	for i := 0; i < min(encoder.run_len, 4); i++ {
+		encoder.block = append(encoder.block, ch)
+	}
+	if encoder.run_len >= 4 {
+		encoder.in_use[encoder.run_len-4] = true
+		encoder.block = append(encoder.block, byte(encoder.run_len-4))
+	}
+	encoder.run_ch, encoder.run_len = 256, 0
+}
+
+// 循环移位排序(倍增法,每轮按(rank[i],rank[i+k mod n])基数排序),结果写入encoder.sa
+func (encoder *bzip2_encoder) sort_rotations() {
+	block := encoder.block
+	n := len(block)
+	grow := func(s []int) []int {
+		if cap(s) < n {
+			return make([]int, n)
+		}
+		return s[:n]
+	}
+	encoder.sa, encoder.rank, encoder.next_rank, encoder.second = grow(encoder.sa), grow(encoder.rank), grow(encoder.next_rank), grow(encoder.second)
+	if len(encoder.counts) < max(n, 256)+1 {
+		encoder.counts = make([]int, max(n, 256)+1)
+	}
+	sa, rank, next_rank, second, counts := encoder.sa, encoder.rank, encoder.next_rank, encoder.second, encoder.counts
+	counting_sort := func(order []int, classes int) {
+		clear(counts[:classes+1])
+		for _, i := range order {
+			counts[rank[i]+1]++
+		}
+		for c := 1; c <= classes; c++ {
+			counts[c] += counts[c-1]
+		}
+		for _, i := range order {
+			sa[counts[rank[i]]] = i
+			counts[rank[i]]++
+		}
+	}
+	for i, b := range block {
+		rank[i] = int(b)
+		second[i] = i
+	}
+	classes := 256
+	counting_sort(second, classes)
+	for k := 1; k < n; k <<= 1 {
+		//按第二关键字rank[i+k]排序
+		for j, i := range sa {
+			i -= k
+			if i < 0 {
+				i += n
+			}
+			second[j] = i
+		}
+		counting_sort(second, classes)
+		key := func(i int) int {
+			i += k
+			if i >= n {
+				i -= n
+			}
+			return rank[i]
+		}
+		next_rank[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			a, b := sa[j-1], sa[j]
+			next_rank[b] = next_rank[a]
+			if rank[a] != rank[b] || key(a) != key(b) {
+				next_rank[b]++
+			}
+		}
+		rank, next_rank = next_rank, rank
+		classes = rank[sa[n-1]] + 1
+		if classes == n {
+			break
+		}
+	}
+	encoder.rank, encoder.next_rank = rank, next_rank
+}
+
+// 压缩并输出当前块
+func (encoder *bzip2_encoder) end_block() {
+	n := len(encoder.block)
+	if n == 0 {
+		return
+	}
+	block_crc := ^encoder.crc
+	encoder.combined = (encoder.combined<<1 | encoder.combined>>31) ^ block_crc
+	encoder.sort_rotations()
+	origin := 0
+	for j, i := range encoder.sa {
+		if i == 0 {
+			origin = j
+		}
+	}
+	for _, b := range []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59} {
+		encoder.bits.write(8, uint32(b))
+	}
+	encoder.bits.write(32, block_crc)
+	encoder.bits.write(1, 0)
+	encoder.bits.write(24, uint32(origin))
+	encoder.generate_mtf_values()
+	encoder.send_mtf_values()
+	encoder.block = encoder.block[:0]
+	encoder.in_use = [256]bool{}
+	encoder.crc = 0xffffffff
+}
+
+// 出现过的字节个数及字节->序号映射
+func (encoder *bzip2_encoder) symbol_map() (int, [256]byte) {
+	var unseq_to_seq [256]byte
+	in_use := 0
+	for i, used := range encoder.in_use {
+		if used {
+			unseq_to_seq[i] = byte(in_use)
+			in_use++
+		}
+	}
+	return in_use, unseq_to_seq
+}
+
+// BWT输出列的MTF变换,零游程以RUNA/RUNB按双射二进制编码,末尾为EOB
+func (encoder *bzip2_encoder) generate_mtf_values() {
+	n := len(encoder.block)
+	in_use, unseq_to_seq := encoder.symbol_map()
+	EOB := uint16(in_use + 1)
+	var list [256]byte
+	for i := range list {
+		list[i] = byte(i)
+	}
+	mtf := encoder.mtf[:0]
+	zeros := 0
+	flush_zeros := func() {
+		if zeros == 0 {
+			return
+		}
+		zeros--
+		for {
+			mtf = append(mtf, uint16(bzip2_run_a+zeros&1))
+			if zeros < 2 {
+				break
+			}
+			zeros = (zeros - 2) / 2
+		}
+		zeros = 0
+	}
+	for _, i := range encoder.sa {
+		j := i - 1
+		if j < 0 {
+			j += n
+		}
+		symbol := unseq_to_seq[encoder.block[j]]
+		if list[0] == symbol {
+			zeros++
+			continue
+		}
+		flush_zeros()
+		position := 1
+		for list[position] != symbol {
+			position++
+		}
+		copy(list[1:position+1], list[:position])
+		list[0] = symbol
+		mtf = append(mtf, uint16(position+1))
+	}
+	flush_zeros()
+	encoder.mtf = append(mtf, EOB)
+}
+
+// Huffman码长(与libbzip2的BZ2_hbMakeCodeLengths一致):权重低8位记录子树深度,
+// 码长超过max_length时将频数减半后重建
+func bzip2_code_lengths(lengths []uint8, freq []int, max_length int) {
+	alpha_size := len(freq)
+	heap := make([]int, alpha_size+2)
+	weight := make([]int, alpha_size*2)
+	parent := make([]int, alpha_size*2)
+	for i, f := range freq {
+		weight[i+1] = max(f, 1) << 8
+	}
+	for {
+		nodes := alpha_size
+		heap_size := 0
+		heap[0], weight[0], parent[0] = 0, 0, -2
+		up_heap := func(z int) {
+			tmp := heap[z]
+			for weight[tmp] < weight[heap[z>>1]] {
+				heap[z] = heap[z>>1]
+				z >>= 1
+			}
+			heap[z] = tmp
+		}
+		down_heap := func(z int) {
+			tmp := heap[z]
+			for {
+				y := z << 1
+				if y > heap_size {
+					break
+				}
+				if y < heap_size && weight[heap[y+1]] < weight[heap[y]] {
+					y++
+				}
+				if weight[tmp] < weight[heap[y]] {
+					break
+				}
+				heap[z] = heap[y]
+				z = y
+			}
+			heap[z] = tmp
+		}
+		for i := 1; i <= alpha_size; i++ {
+			parent[i] = -1
+			heap_size++
+			heap[heap_size] = i
+			up_heap(heap_size)
+		}
+		for heap_size > 1 {
+			n1 := heap[1]
+			heap[1] = heap[heap_size]
+			heap_size--
+			down_heap(1)
+			n2 := heap[1]
+			heap[1] = heap[heap_size]
+			heap_size--
+			down_heap(1)
+			nodes++
+			parent[n1], parent[n2] = nodes, nodes
+			weight[nodes] = (weight[n1]&^0xff + weight[n2]&^0xff) | (1 + max(weight[n1]&0xff, weight[n2]&0xff))
+			parent[nodes] = -1
+			heap_size++
+			heap[heap_size] = nodes
+			up_heap(heap_size)
+		}
+		too_long := false
+		for i := 1; i <= alpha_size; i++ {
+			depth := 0
+			for k := i; parent[k] >= 0; k = parent[k] {
+				depth++
+			}
+			lengths[i-1] = uint8(depth)
+			too_long = too_long || depth > max_length
+		}
+		if !too_long {
+			return
+		}
+		for i := 1; i <= alpha_size; i++ {
+			weight[i] = (1 + (weight[i]>>8)/2) << 8
+		}
+	}
+}
+
+// 选择编码表、输出映射表、选择子、编码表与Huffman编码后的MTF符号(与libbzip2的sendMTFValues一致)
+func (encoder *bzip2_encoder) send_mtf_values() {
+	const lesser_cost, greater_cost = 0, 15
+	mtf := encoder.mtf
+	in_use, _ := encoder.symbol_map()
+	alpha_size := in_use + 2
+	frequency := make([]int, alpha_size)
+	for _, v := range mtf {
+		frequency[v]++
+	}
+	groups := 6
+	switch {
+	case len(mtf) < 200:
+		groups = 2
+	case len(mtf) < 600:
+		groups = 3
+	case len(mtf) < 1200:
+		groups = 4
+	case len(mtf) < 2400:
+		groups = 5
+	}
+	var lengths [bzip2_max_groups][]uint8
+	for t := range lengths {
+		lengths[t] = make([]uint8, alpha_size)
+	}
+	//初始编码表:按频数将符号分为groups段,每个表对其中一段取低代价
+	remaining := len(mtf)
+	start := 0
+	for part := groups; part > 0; part-- {
+		target := remaining / part
+		end := start - 1
+		sum := 0
+		for sum < target && end < alpha_size-1 {
+			end++
+			sum += frequency[end]
+		}
+		if end > start && part != groups && part != 1 && (groups-part)%2 == 1 {
+			sum -= frequency[end]
+			end--
+		}
+		for v := range alpha_size {
+			if v >= start && v <= end {
+				lengths[part-1][v] = lesser_cost
+			} else {
+				lengths[part-1][v] = greater_cost
+			}
+		}
+		start = end + 1
+		remaining -= sum
+	}
+	//迭代:每组选代价最小的表,再按各表累计的频数重建码长
+	selectors := make([]int, 0, (len(mtf)+bzip2_group_size-1)/bzip2_group_size)
+	var table_frequency [bzip2_max_groups][]int
+	for t := range table_frequency {
+		table_frequency[t] = make([]int, alpha_size)
+	}
+	for range bzip2_iterations {
+		for t := range groups {
+			clear(table_frequency[t])
+		}
+		selectors = selectors[:0]
+		for start := 0; start < len(mtf); start += bzip2_group_size {
+			end := min(start+bzip2_group_size, len(mtf))
+			best, best_cost := -1, 999999999
+			for t := range groups {
+				cost := 0
+				for _, v := range mtf[start:end] {
+					cost += int(lengths[t][v])
+				}
+				if cost < best_cost {
+					best, best_cost = t, cost
+				}
+			}
+			selectors = append(selectors, best)
+			for _, v := range mtf[start:end] {
+				table_frequency[best][v]++
+			}
+		}
+		for t := range groups {
+			bzip2_code_lengths(lengths[t], table_frequency[t], bzip2_max_code_length)
+		}
+	}
+	//规范Huffman码:按码长从短到长、同码长按符号顺序分配
+	var codes [bzip2_max_groups][]uint32
+	for t := range groups {
+		codes[t] = make([]uint32, alpha_size)
+		min_length, max_length := uint8(32), uint8(0)
+		for _, l := range lengths[t] {
+			min_length, max_length = min(min_length, l), max(max_length, l)
+		}
+		code := uint32(0)
+		for l := min_length; l <= max_length; l++ {
+			for v := range alpha_size {
+				if lengths[t][v] == l {
+					codes[t][v] = code
+					code++
+				}
+			}
+			code <<= 1
+		}
+	}
+	//映射表:16个16字节区间是否出现,再逐字节标记出现过的区间
+	bits := &encoder.bits
+	var in_use_16 [16]bool
+	for i := range 256 {
+		in_use_16[i/16] = in_use_16[i/16] || encoder.in_use[i]
+	}
+	for _, used := range in_use_16 {
+		bits.write(1, bool_bit(used))
+	}
+	for i, used := range in_use_16 {
+		if used {
+			for j := range 16 {
+				bits.write(1, bool_bit(encoder.in_use[i*16+j]))
+			}
+		}
+	}
+	//选择子:MTF变换后以一元码输出
+	bits.write(3, uint32(groups))
+	bits.write(15, uint32(len(selectors)))
+	var positions [bzip2_max_groups]int
+	for i := range positions {
+		positions[i] = i
+	}
+	for _, selector := range selectors {
+		j := 0
+		for positions[j] != selector {
+			j++
+		}
+		copy(positions[1:j+1], positions[:j])
+		positions[0] = selector
+		for range j {
+			bits.write(1, 1)
+		}
+		bits.write(1, 0)
+	}
+	//编码表:首个码长5比特,其后各码长相对前一个的增量(10为加1,11为减1,0结束)
+	for t := range groups {
+		current := lengths[t][0]
+		bits.write(5, uint32(current))
+		for _, l := range lengths[t] {
+			for ; current < l; current++ {
+				bits.write(2, 2)
+			}
+			for ; current > l; current-- {
+				bits.write(2, 3)
+			}
+			bits.write(1, 0)
+		}
+	}
+	for g, selector := range selectors {
+		for _, v := range mtf[g*bzip2_group_size : min((g+1)*bzip2_group_size, len(mtf))] {
+			bits.write(uint(lengths[selector][v]), codes[selector][v])
+		}
+	}
+}
+
+// bool->比特
+func bool_bit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package estimate
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	z_alpha           = 2.5758293035489 //标准正态分布99.5%分位数,用于全局可预测性上界
+	local_alpha       = 0.99            //局部可预测性求解的目标概率
+	local_iterations  = 10              //局部可预测性中x的迭代次数
+	binary_iterations = 64              //二分查找的迭代次数
+)
+
+// 预测器估计结果结构体
+type Predictor_Result struct {
+	Name           string  //预测器名称
+	N              int     //预测次数
+	C              int     //正确预测次数
+	R              int     //最长正确预测游程长度加1
+	P_Global       float64 //全局可预测性
+	P_Global_Prime float64 //全局可预测性上界
+	P_Local        float64 //局部可预测性
+	Min_Entropy    float64 //最小熵(单位:比特/符号),不适用时为0
+	Applicable     bool    //是否适用,预测次数不足2时不适用,不参与取最小值
+}
+
+// 原始样本->符号序列(每个符号占一个字节,取低bits_per_symbol位)
+func Bytes2Symbols(bytes []byte, bits_per_symbol int) []byte {
+	if bits_per_symbol < 1 || bits_per_symbol > 8 {
+		fmt.Println("Bytes2Symbols error!")
+		return nil
+	}
+	symbols := make([]byte, 0, len(bytes)*8/bits_per_symbol)
+	mask := byte(1<<bits_per_symbol - 1)
+	if bits_per_symbol == 8 {
+		return append(symbols, bytes...)
+	}
+	buf := 0
+	buf_length := 0
+	for _, b := range bytes {
+		buf = buf<<8 | int(b)
+		buf_length += 8
+		for buf_length >= bits_per_symbol {
+			buf_length -= bits_per_symbol
+			symbols = append(symbols, byte(buf>>buf_length)&mask)
+		}
+		buf &= 1<<buf_length - 1
+	}
+	return symbols
+}
+
+// 符号序列的字母表大小
+func Alphabet_Size(symbols []byte) int {
+	var seen [256]bool
+	k := 0
+	for _, s := range symbols {
+		if !seen[s] {
+			seen[s] = true
+			k++
+		}
+	}
+	return k
+}
+
+// 最长正确预测游程长度
+func Calc_Run(correct []bool) int {
+	run := 0
+	max_run := 0
+	for _, c := range correct {
+		if c {
+			run++
+			if run > max_run {
+				max_run = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	return max_run
+}
+
+// 全局可预测性上界
+func Calc_P_Global(C int, N int) (float64, float64) {
+	P_global := float64(C) / float64(N)
+	if C == 0 {
+		return P_global, 1 - math.Pow(0.01, 1/float64(N))
+	}
+	return P_global, min(1, P_global+z_alpha*math.Sqrt(P_global*(1-P_global)/float64(N-1)))
+}
+
+// 长度为N的序列中最长游程小于r的概率(以对数形式计算,避免x^(N+1)溢出)
+func run_probability(p float64, r int, N int) float64 {
+	q := 1 - p
+	if q <= 0 {
+		return 0
+	}
+	x := 1.0
+	for j := 0; j < local_iterations; j++ {
+		x = 1 + q*math.Pow(p, float64(r))*math.Pow(x, float64(r+1))
+	}
+	numerator := 1 - p*x
+	denominator := (float64(r+1) - float64(r)*x) * q
+	if numerator <= 0 || denominator <= 0 {
+		return 0
+	}
+	return math.Exp(math.Log(numerator) - math.Log(denominator) - float64(N+1)*math.Log(x))
+}
+
+// 局部可预测性:求解使最长游程小于r的概率为0.99的p
+func Calc_P_Local(N int, r int) float64 {
+	low := 0.0
+	high := 1.0
+	for i := 0; i < binary_iterations; i++ {
+		mid := (low + high) / 2
+		if run_probability(mid, r, N) > local_alpha {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high
+}
+
+// 由预测结果计算最小熵,预测次数不足2(序列短于预测器的窗口或深度)时不适用
+func Predictor_Estimate(name string, correct []bool, alphabet_size int) Predictor_Result {
+	result := Predictor_Result{Name: name, N: len(correct)}
+	if result.N < 2 {
+		return result
+	}
+	result.Applicable = true
+	for _, c := range correct {
+		if c {
+			result.C++
+		}
+	}
+	result.R = Calc_Run(correct) + 1
+	result.P_Global, result.P_Global_Prime = Calc_P_Global(result.C, result.N)
+	result.P_Local = Calc_P_Local(result.N, result.R)
+	P_max := max(result.P_Global_Prime, result.P_Local, 1/float64(alphabet_size))
+	result.Min_Entropy = -math.Log2(P_max)
+	return result
+}
@@ -0,0 +1,548 @@
+package estimate
+
+import (
+	crypto_rand "crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	math_rand "math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	permutation_shuffles = 10000 //置换检验的洗牌次数
+	chi_square_alpha     = 0.001 //卡方检验与LRS检验的显著性水平
+	chi_square_min_bin   = 5     //卡方检验每个分组的最小期望次数
+)
+
+var permutation_lags = []int{1, 2, 8, 16, 32} //周期性检验与协方差检验的延迟
+
+// 置换检验的统计量名称,与permutation_statistics的返回顺序一致
+var Permutation_Names = []string{
+	"excursion",
+	"numDirectionalRuns",
+	"lenDirectionalRuns",
+	"numIncreasesDecreases",
+	"numRunsMedian",
+	"lenRunsMedian",
+	"avgCollision",
+	"maxCollision",
+	"periodicity(1)",
+	"periodicity(2)",
+	"periodicity(8)",
+	"periodicity(16)",
+	"periodicity(32)",
+	"covariance(1)",
+	"covariance(2)",
+	"covariance(8)",
+	"covariance(16)",
+	"covariance(32)",
+	"compression",
+}
+
+// IID检验配置
+type IID_Config struct {
+	Shuffles      int    //洗牌次数
+	Workers       int    //并行的goroutine数量
+	Deterministic bool   //是否使用固定种子,便于复现
+	Seed          uint64 //固定种子
+}
+
+// 置换检验单项结果
+type Permutation_Result struct {
+	Name string  //统计量名称
+	T    float64 //原始序列的统计量
+	C0   int     //洗牌后统计量大于原始统计量的次数
+	C1   int     //洗牌后统计量等于原始统计量的次数
+	Pass bool    //是否通过
+}
+
+// 卡方检验结果
+type Chi_Square_Result struct {
+	Name    string  //检验名称
+	T       float64 //卡方统计量
+	Df      int     //自由度
+	P_Value float64 //P值
+	Pass    bool    //是否通过
+}
+
+// 最长重复子串检验结果
+type LRS_Result struct {
+	W     int     //最长重复子串的长度
+	P_Col float64 //碰撞概率
+	P     float64 //至少出现一次长度为W的重复的概率
+	Pass  bool    //是否通过
+}
+
+// IID检验总结果
+type IID_Result struct {
+	Permutation []Permutation_Result //置换检验结果
+	Chi_Square  []Chi_Square_Result  //卡方检验结果
+	LRS         LRS_Result           //最长重复子串检验结果
+	Pass        bool                 //是否全部通过
+}
+
+// 默认IID检验配置
+func Default_IID_Config() IID_Config {
+	return IID_Config{Shuffles: permutation_shuffles, Workers: runtime.NumCPU()}
+}
+
+// 判断符号序列是否为二元序列
+func Is_Binary(symbols []byte) bool {
+	for _, s := range symbols {
+		if s > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// 转换I:每8比特一组,统计1的个数
+func Conversion_I(symbols []byte) []byte {
+	converted := make([]byte, len(symbols)/8)
+	for i := range converted {
+		for j := 0; j < 8; j++ {
+			converted[i] += symbols[i*8+j]
+		}
+	}
+	return converted
+}
+
+// 转换II:每8比特一组,转换为整数
+func Conversion_II(symbols []byte) []byte {
+	converted := make([]byte, len(symbols)/8)
+	for i := range converted {
+		for j := 0; j < 8; j++ {
+			converted[i] = converted[i]<<1 | symbols[i*8+j]
+		}
+	}
+	return converted
+}
+
+// 中位数
+func Median(symbols []byte) float64 {
+	sorted := slices.Clone(symbols)
+	slices.Sort(sorted)
+	L := len(sorted)
+	if L%2 == 1 {
+		return float64(sorted[L/2])
+	}
+	return (float64(sorted[L/2-1]) + float64(sorted[L/2])) / 2
+}
+
+// 游程个数与最长游程长度
+func runs(signs []bool) (int, int) {
+	if len(signs) == 0 {
+		return 0, 0
+	}
+	number := 1
+	length := 1
+	longest := 1
+	for i := 1; i < len(signs); i++ {
+		if signs[i] == signs[i-1] {
+			length++
+		} else {
+			number++
+			length = 1
+		}
+		longest = max(longest, length)
+	}
+	return number, longest
+}
+
+// 偏移检验统计量
+func excursion(symbols []byte, mean float64) float64 {
+	sum := 0.0
+	T := 0.0
+	for i, s := range symbols {
+		sum += float64(s)
+		T = max(T, math.Abs(sum-float64(i+1)*mean))
+	}
+	return T
+}
+
+// 方向游程统计量:游程个数、最长游程长度、增减次数的较大者
+func directional_runs(symbols []byte, signs []bool) (float64, float64, float64) {
+	signs = signs[:0]
+	increases := 0
+	for i := 0; i+1 < len(symbols); i++ {
+		increase := symbols[i] <= symbols[i+1]
+		if increase {
+			increases++
+		}
+		signs = append(signs, increase)
+	}
+	number, longest := runs(signs)
+	return float64(number), float64(longest), float64(max(increases, len(signs)-increases))
+}
+
+// 基于中位数的游程统计量:游程个数、最长游程长度
+func median_runs(symbols []byte, median float64, signs []bool) (float64, float64) {
+	signs = signs[:0]
+	for _, s := range symbols {
+		signs = append(signs, float64(s) >= median)
+	}
+	number, longest := runs(signs)
+	return float64(number), float64(longest)
+}
+
+// 碰撞统计量:平均碰撞长度、最大碰撞长度
+func collision(symbols []byte) (float64, float64) {
+	var seen [256]int
+	epoch := 0
+	count := 0
+	sum := 0
+	longest := 0
+	i := 0
+	for i < len(symbols) {
+		epoch++
+		j := i
+		for j < len(symbols) && seen[symbols[j]] != epoch {
+			seen[symbols[j]] = epoch
+			j++
+		}
+		if j == len(symbols) {
+			break
+		}
+		count++
+		sum += j - i + 1
+		longest = max(longest, j-i+1)
+		i = j + 1
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(sum) / float64(count), float64(longest)
+}
+
+// 周期性统计量与协方差统计量
+func periodicity_covariance(symbols []byte, p int) (float64, float64) {
+	periodicity := 0
+	covariance := 0
+	for i := 0; i+p < len(symbols); i++ {
+		if symbols[i] == symbols[i+p] {
+			periodicity++
+		}
+		covariance += int(symbols[i]) * int(symbols[i+p])
+	}
+	return float64(periodicity), float64(covariance)
+}
+
+// 压缩统计量(SP 800-90B 5.1.11):以空格分隔的十进制字符串经bzip2压缩后的长度
+func compression(symbols []byte, scratch *permutation_scratch) float64 {
+	text := scratch.Text[:0]
+	for i, s := range symbols {
+		if i > 0 {
+			text = append(text, ' ')
+		}
+		text = strconv.AppendInt(text, int64(s), 10)
+	}
+	scratch.Text = text
+	return float64(len(scratch.Encoder.compress(text)))
+}
+
+// 置换检验的工作区,每个goroutine独占一份
+type permutation_scratch struct {
+	Signs   []bool        //游程检验的符号序列
+	Text    []byte        //压缩检验的文本
+	Encoder bzip2_encoder //压缩检验的bzip2压缩器
+}
+
+// 计算全部置换检验统计量
+func permutation_statistics(symbols []byte, is_binary bool, median float64, mean float64, scratch *permutation_scratch) []float64 {
+	T := make([]float64, 0, len(Permutation_Names))
+	converted_I := symbols
+	converted_II := symbols
+	if is_binary {
+		converted_I = Conversion_I(symbols)
+		converted_II = Conversion_II(symbols)
+	}
+	T = append(T, excursion(symbols, mean))
+	number, longest, increases := directional_runs(converted_I, scratch.Signs)
+	T = append(T, number, longest, increases)
+	number, longest = median_runs(symbols, median, scratch.Signs)
+	T = append(T, number, longest)
+	average, maximum := collision(converted_II)
+	T = append(T, average, maximum)
+	covariances := make([]float64, 0, len(permutation_lags))
+	for _, p := range permutation_lags {
+		periodicity, covariance := periodicity_covariance(converted_I, p)
+		T = append(T, periodicity)
+		covariances = append(covariances, covariance)
+	}
+	T = append(T, covariances...)
+	T = append(T, compression(symbols, scratch))
+	return T
+}
+
+// 置换检验(SP 800-90B 5.1)
+func Permutation_Tests(symbols []byte, config IID_Config) []Permutation_Result {
+	is_binary := Is_Binary(symbols)
+	median := Median(symbols)
+	if is_binary {
+		median = 0.5
+	}
+	mean := 0.0
+	for _, s := range symbols {
+		mean += float64(s)
+	}
+	mean /= float64(len(symbols))
+	if !config.Deterministic {
+		seed := make([]byte, 8)
+		_, _ = crypto_rand.Read(seed)
+		config.Seed = binary.BigEndian.Uint64(seed)
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	new_scratch := func() *permutation_scratch {
+		return &permutation_scratch{Signs: make([]bool, 0, len(symbols)), Text: make([]byte, 0, len(symbols)*4)}
+	}
+	T := permutation_statistics(symbols, is_binary, median, mean, new_scratch())
+	C0 := make([]int, len(T))
+	C1 := make([]int, len(T))
+	indexes := make(chan int)
+	var lock sync.Mutex
+	var wait_group sync.WaitGroup
+	for w := 0; w < config.Workers; w++ {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			scratch := new_scratch()
+			shuffled := make([]byte, len(symbols))
+			c0 := make([]int, len(T))
+			c1 := make([]int, len(T))
+			for index := range indexes {
+				copy(shuffled, symbols)
+				random := math_rand.New(math_rand.NewPCG(config.Seed, uint64(index)))
+				random.Shuffle(len(shuffled), func(i int, j int) {
+					shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+				})
+				T_shuffled := permutation_statistics(shuffled, is_binary, median, mean, scratch)
+				for i := range T {
+					if T_shuffled[i] > T[i] {
+						c0[i]++
+					} else if T_shuffled[i] == T[i] {
+						c1[i]++
+					}
+				}
+			}
+			lock.Lock()
+			for i := range T {
+				C0[i] += c0[i]
+				C1[i] += c1[i]
+			}
+			lock.Unlock()
+		}()
+	}
+	for index := 0; index < config.Shuffles; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wait_group.Wait()
+	results := make([]Permutation_Result, len(T))
+	for i := range T {
+		results[i] = Permutation_Result{Name: Permutation_Names[i], T: T[i], C0: C0[i], C1: C1[i]}
+		results[i].Pass = C0[i]+C1[i] > 5 && C0[i] < config.Shuffles-5
+	}
+	return results
+}
+
+// 卡方检验结果判定
+func chi_square_result(name string, T float64, df int) Chi_Square_Result {
+	result := Chi_Square_Result{Name: name, T: T, Df: df}
+	if df < 1 {
+		result.P_Value = 1
+		result.Pass = true
+		return result
+	}
+	result.P_Value = tools.Igamc(float64(df)/2, T/2)
+	result.Pass = result.P_Value >= chi_square_alpha
+	return result
+}
+
+// 按期望次数从小到大分组,使每组期望次数不小于5,返回每个取值所在的组号及各组期望次数
+func chi_square_bins(expected []float64) ([]int, []float64) {
+	order := make([]int, len(expected))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a int, b int) int {
+		if expected[a] < expected[b] {
+			return -1
+		} else if expected[a] > expected[b] {
+			return 1
+		}
+		return 0
+	})
+	bin_of := make([]int, len(expected))
+	bins := make([]float64, 0)
+	current := 0.0
+	for _, i := range order {
+		if expected[i] == 0 {
+			bin_of[i] = -1
+			continue
+		}
+		current += expected[i]
+		bin_of[i] = len(bins)
+		if current >= chi_square_min_bin {
+			bins = append(bins, current)
+			current = 0
+		}
+	}
+	if current > 0 {
+		if len(bins) == 0 {
+			bins = append(bins, current)
+		} else {
+			bins[len(bins)-1] += current
+			for i := range bin_of {
+				if bin_of[i] == len(bins) {
+					bin_of[i] = len(bins) - 1
+				}
+			}
+		}
+	}
+	return bin_of, bins
+}
+
+// 卡方独立性检验(SP 800-90B 5.2.1与5.2.3)
+func Chi_Square_Independence(symbols []byte) Chi_Square_Result {
+	L := len(symbols)
+	if Is_Binary(symbols) {
+		ones := 0
+		for _, s := range symbols {
+			ones += int(s)
+		}
+		p1 := float64(ones) / float64(L)
+		p0 := 1 - p1
+		m := 0
+		for j := 2; j <= 11; j++ {
+			if float64(L/j)*math.Pow(min(p0, p1), float64(j)) >= chi_square_min_bin {
+				m = j
+			}
+		}
+		if m < 2 {
+			return chi_square_result("independence", 0, 0)
+		}
+		observed := make([]int, 1<<m)
+		for i := 0; i+m <= L; i += m {
+			value := 0
+			for j := 0; j < m; j++ {
+				value = value<<1 | int(symbols[i+j])
+			}
+			observed[value]++
+		}
+		T := 0.0
+		for value, o := range observed {
+			w := float64(bits.OnesCount(uint(value)))
+			e := math.Pow(p1, w) * math.Pow(p0, float64(m)-w) * float64(L/m)
+			T += (float64(o) - e) * (float64(o) - e) / e
+		}
+		return chi_square_result("independence", T, 1<<m-2)
+	}
+	var counts [256]int
+	for _, s := range symbols {
+		counts[s]++
+	}
+	k := 0
+	for _, c := range counts {
+		if c > 0 {
+			k++
+		}
+	}
+	expected := make([]float64, 256*256)
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			expected[a*256+b] = float64(counts[a]) * float64(counts[b]) / float64(L) / float64(L) * float64(L-1)
+		}
+	}
+	bin_of, bins := chi_square_bins(expected)
+	observed := make([]float64, len(bins))
+	for i := 0; i+1 < L; i++ {
+		observed[bin_of[int(symbols[i])*256+int(symbols[i+1])]]++
+	}
+	T := 0.0
+	for i, e := range bins {
+		T += (observed[i] - e) * (observed[i] - e) / e
+	}
+	return chi_square_result("independence", T, len(bins)-k)
+}
+
+// 卡方拟合优度检验(SP 800-90B 5.2.2与5.2.4)
+func Chi_Square_Goodness_Of_Fit(symbols []byte) Chi_Square_Result {
+	L := len(symbols)
+	part := L / 10
+	var counts [256]int
+	for _, s := range symbols[:part*10] {
+		counts[s]++
+	}
+	expected := make([]float64, 256)
+	for i, c := range counts {
+		expected[i] = float64(c) / 10
+	}
+	bin_of, bins := chi_square_bins(expected)
+	if Is_Binary(symbols) {
+		bin_of = []int{0, 1}
+		bins = expected[:2]
+	}
+	T := 0.0
+	for d := 0; d < 10; d++ {
+		observed := make([]float64, len(bins))
+		for _, s := range symbols[d*part : (d+1)*part] {
+			observed[bin_of[s]]++
+		}
+		for i, e := range bins {
+			if e > 0 {
+				T += (observed[i] - e) * (observed[i] - e) / e
+			}
+		}
+	}
+	return chi_square_result("goodnessOfFit", T, 9*(len(bins)-1))
+}
+
+// 最长重复子串的长度,即LCP数组的最大值
+func Longest_Repeated_Substring(symbols []byte) int {
+	_, _, longest := tuple_statistics(symbols)
+	return longest
+}
+
+// 最长重复子串检验(SP 800-90B 5.2.5)
+func LRS_Test(symbols []byte) LRS_Result {
+	L := len(symbols)
+	var counts [256]int
+	for _, s := range symbols {
+		counts[s]++
+	}
+	result := LRS_Result{W: Longest_Repeated_Substring(symbols)}
+	for _, c := range counts {
+		p := float64(c) / float64(L)
+		result.P_Col += p * p
+	}
+	N := float64(L - result.W + 1)
+	pairs := N * (N - 1) / 2
+	result.P = -math.Expm1(pairs * math.Log1p(-math.Pow(result.P_Col, float64(result.W))))
+	result.Pass = result.P >= chi_square_alpha
+	return result
+}
+
+// IID检验(置换检验、卡方检验与最长重复子串检验)
+func IID_Tests(symbols []byte, config IID_Config) IID_Result {
+	result := IID_Result{
+		Permutation: Permutation_Tests(symbols, config),
+		Chi_Square:  []Chi_Square_Result{Chi_Square_Independence(symbols), Chi_Square_Goodness_Of_Fit(symbols)},
+		LRS:         LRS_Test(symbols),
+	}
+	result.Pass = result.LRS.Pass
+	for _, r := range result.Permutation {
+		result.Pass = result.Pass && r.Pass
+	}
+	for _, r := range result.Chi_Square {
+		result.Pass = result.Pass && r.Pass
+	}
+	return result
+}
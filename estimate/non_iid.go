@@ -0,0 +1,330 @@
+package estimate
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	tuple_cutoff     = 35      //t元组估计与LRS估计中元组出现次数的阈值
+	compression_b    = 6       //压缩估计的块长度(单位:比特)
+	compression_d    = 1000    //压缩估计的字典块数量
+	compression_c    = 0.5907  //压缩估计的标准差修正系数
+	markov_length    = 128     //Markov估计的序列长度
+	bitstring_length = 1000000 //比特串估计使用的最大比特数
+)
+
+// 单项估计结果
+type Estimator_Result struct {
+	Name        string  //估计方法名称
+	Min_Entropy float64 //最小熵(单位:比特/符号),不适用时为0
+	Applicable  bool    //估计方法是否适用,不适用的结果不参与取最小值
+}
+
+// 非IID熵源估计报告
+type NonIID_Report struct {
+	Bits_Per_Symbol int                //每个符号的比特数
+	Samples         int                //符号个数
+	Original        []Estimator_Result //原始符号序列的估计结果
+	Bitstring       []Estimator_Result //比特串的估计结果(仅多比特符号)
+	H_Original      float64            //原始符号序列的最小熵
+	H_Bitstring     float64            //比特串的最小熵(单位:比特/比特)
+	H_Final         float64            //最终最小熵(单位:比特/符号)
+}
+
+// 置信上界
+func upper_bound(p float64, L int) float64 {
+	return min(1, p+z_alpha*math.Sqrt(p*(1-p)/float64(L-1)))
+}
+
+// 最常见值估计(SP 800-90B 6.3.1)
+func MCV_Test(symbols []byte) float64 {
+	L := len(symbols)
+	var counts [256]int
+	for _, s := range symbols {
+		counts[s]++
+	}
+	p := float64(slices.Max(counts[:])) / float64(L)
+	return -math.Log2(upper_bound(p, L))
+}
+
+// 二分查找单调函数f在[low,high]上取值为target的点,increasing表示f是否递增,无解时返回false
+func binary_search(f func(float64) float64, target float64, low float64, high float64, increasing bool) (float64, bool) {
+	f_low := f(low)
+	f_high := f(high)
+	if (increasing && (target < f_low || target > f_high)) || (!increasing && (target > f_low || target < f_high)) {
+		return 0, false
+	}
+	for i := 0; i < binary_iterations; i++ {
+		mid := (low + high) / 2
+		if (f(mid) < target) == increasing {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, true
+}
+
+// 平均值与标准差
+func mean_deviation(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+	return mean, math.Sqrt(variance)
+}
+
+// 碰撞估计(SP 800-90B 6.3.2),仅适用于二元序列
+func Collision_Test(symbols []byte) float64 {
+	t := make([]float64, 0)
+	i := 0
+	for i+1 < len(symbols) {
+		j := i + 1
+		if symbols[j] != symbols[i] {
+			j++
+			if j >= len(symbols) {
+				break
+			}
+		}
+		t = append(t, float64(j-i+1))
+		i = j + 1
+	}
+	if len(t) < 2 {
+		return 1
+	}
+	mean, deviation := mean_deviation(t)
+	mean -= z_alpha * deviation / math.Sqrt(float64(len(t)))
+	if mean >= 2.5 {
+		return 1
+	}
+	if mean <= 2 {
+		return 0
+	}
+	//二元序列时6.3.2中的期望碰撞时间化简为2+2p(1-p),直接求解p∈[0.5,1]
+	p := (1 + math.Sqrt(1-2*(mean-2))) / 2
+	return -math.Log2(p)
+}
+
+// Markov估计(SP 800-90B 6.3.3),仅适用于二元序列
+func Markov_Test(symbols []byte) float64 {
+	L := len(symbols)
+	var counts [2]float64
+	var transitions [2][2]float64
+	for i, s := range symbols {
+		counts[s]++
+		if i+1 < L {
+			transitions[s][symbols[i+1]]++
+		}
+	}
+	P0 := counts[0] / float64(L)
+	P1 := counts[1] / float64(L)
+	var T [2][2]float64
+	for a := 0; a < 2; a++ {
+		total := transitions[a][0] + transitions[a][1]
+		for b := 0; b < 2; b++ {
+			if total > 0 {
+				T[a][b] = transitions[a][b] / total
+			}
+		}
+	}
+	log2 := func(x float64) float64 {
+		if x == 0 {
+			return math.Inf(-1)
+		}
+		return math.Log2(x)
+	}
+	n := float64(markov_length)
+	candidates := []float64{
+		log2(P0) + (n-1)*log2(T[0][0]),
+		log2(P0) + (n/2)*log2(T[0][1]) + (n/2-1)*log2(T[1][0]),
+		log2(P0) + log2(T[0][1]) + (n-2)*log2(T[1][1]),
+		log2(P1) + log2(T[1][0]) + (n-2)*log2(T[0][0]),
+		log2(P1) + (n/2)*log2(T[1][0]) + (n/2-1)*log2(T[0][1]),
+		log2(P1) + (n-1)*log2(T[1][1]),
+	}
+	return min(-slices.Max(candidates)/n, 1)
+}
+
+// 压缩估计中的G函数
+func compression_G(z float64, d int, v int) float64 {
+	L := d + v
+	sum := 0.0
+	power := 1.0
+	for u := 1; u < L; u++ {
+		log_u := math.Log2(float64(u))
+		sum += log_u * z * z * power * float64(L-max(u, d))
+		if u > d {
+			sum += log_u * z * power
+		}
+		power *= 1 - z
+	}
+	sum += math.Log2(float64(L)) * z * power
+	return sum / float64(v)
+}
+
+// 压缩估计(SP 800-90B 6.3.4),仅适用于二元序列
+func Compression_Test(symbols []byte) float64 {
+	b := compression_b
+	d := compression_d
+	blocks := len(symbols) / b
+	v := blocks - d
+	if v < 2 {
+		return 1
+	}
+	dictionary := make([]int, 1<<b)
+	log_D := make([]float64, v)
+	for i := 1; i <= blocks; i++ {
+		value := 0
+		for j := 0; j < b; j++ {
+			value = value<<1 | int(symbols[(i-1)*b+j])
+		}
+		if i > d {
+			if dictionary[value] != 0 {
+				log_D[i-d-1] = math.Log2(float64(i - dictionary[value]))
+			} else {
+				log_D[i-d-1] = math.Log2(float64(i))
+			}
+		}
+		dictionary[value] = i
+	}
+	mean := 0.0
+	square := 0.0
+	for _, x := range log_D {
+		mean += x
+		square += x * x
+	}
+	mean /= float64(v)
+	deviation := compression_c * math.Sqrt(max(0, square/float64(v-1)-mean*mean))
+	mean -= z_alpha * deviation / math.Sqrt(float64(v))
+	k := float64(int(1)<<b - 1)
+	f := func(p float64) float64 {
+		q := (1 - p) / k
+		return compression_G(p, d, v) + k*compression_G(q, d, v)
+	}
+	p, ok := binary_search(f, mean, 1/float64(int(1)<<b), 1, false)
+	if !ok {
+		return 1
+	}
+	return -math.Log2(p) / float64(b)
+}
+
+// t元组估计(SP 800-90B 6.3.5),不存在出现次数达到阈值的元组时不适用,返回false
+func TTuple_Test(symbols []byte) (float64, bool) {
+	L := len(symbols)
+	Q, _, _ := tuple_statistics(symbols)
+	p_max := 0.0
+	for t := 1; t < L; t++ {
+		if Q[t] < tuple_cutoff {
+			break
+		}
+		P := float64(Q[t]) / float64(L-t+1)
+		p_max = max(p_max, math.Pow(P, 1/float64(t)))
+	}
+	if p_max == 0 {
+		return 0, false
+	}
+	return -math.Log2(upper_bound(p_max, L)), true
+}
+
+// 最长重复子串估计(SP 800-90B 6.3.6),u>v(无足够长的重复子串)时不适用,返回false
+func LRS_Estimate(symbols []byte) (float64, bool) {
+	L := len(symbols)
+	Q, pairs, v := tuple_statistics(symbols)
+	u := 1
+	for u < L && Q[u] >= tuple_cutoff {
+		u++
+	}
+	if u > v {
+		return 0, false
+	}
+	p_max := 0.0
+	for W := u; W <= v; W++ {
+		N := float64(L - W + 1)
+		P := float64(pairs[W]) / (N * (N - 1) / 2)
+		p_max = max(p_max, math.Pow(P, 1/float64(W)))
+	}
+	return -math.Log2(upper_bound(p_max, L)), true
+}
+
+// 符号序列->比特串(每个符号取低bits_per_symbol位,高位在前)
+func Symbols2Bits(symbols []byte, bits_per_symbol int) []byte {
+	bitstring := make([]byte, 0, len(symbols)*bits_per_symbol)
+	for _, s := range symbols {
+		for j := bits_per_symbol - 1; j >= 0; j-- {
+			bitstring = append(bitstring, s>>j&1)
+		}
+	}
+	return bitstring
+}
+
+// 对一个序列运行全部适用的估计方法
+func non_iid_estimates(symbols []byte, alphabet_size int) []Estimator_Result {
+	results := []Estimator_Result{{"MCV", MCV_Test(symbols), true}}
+	if Is_Binary(symbols) {
+		results = append(results,
+			Estimator_Result{"Collision", Collision_Test(symbols), true},
+			Estimator_Result{"Markov", Markov_Test(symbols), true},
+			Estimator_Result{"Compression", Compression_Test(symbols), true})
+	}
+	t_tuple, t_tuple_applicable := TTuple_Test(symbols)
+	lrs, lrs_applicable := LRS_Estimate(symbols)
+	results = append(results,
+		Estimator_Result{"TTuple", t_tuple, t_tuple_applicable},
+		Estimator_Result{"LRS", lrs, lrs_applicable})
+	for _, result := range Predictor_Tests(symbols, alphabet_size) {
+		results = append(results, Estimator_Result{result.Name, result.Min_Entropy, result.Applicable})
+	}
+	//上界为1时-log2(1)=-0,统一为0
+	for i := range results {
+		results[i].Min_Entropy = max(0, results[i].Min_Entropy)
+	}
+	return results
+}
+
+// 适用的估计结果的最小值
+func min_estimate(results []Estimator_Result) float64 {
+	min_entropy := math.Inf(1)
+	for _, result := range results {
+		if result.Applicable {
+			min_entropy = min(min_entropy, result.Min_Entropy)
+		}
+	}
+	return min_entropy
+}
+
+// 非IID熵源估计全流程:原始符号序列与比特串分别估计,取较小者
+func NonIID_Suite(symbols []byte, bits_per_symbol int) NonIID_Report {
+	report := NonIID_Report{Bits_Per_Symbol: bits_per_symbol, Samples: len(symbols)}
+	report.Original = non_iid_estimates(symbols, 1<<bits_per_symbol)
+	report.H_Original = min(min_estimate(report.Original), float64(bits_per_symbol))
+	report.H_Final = report.H_Original
+	if bits_per_symbol > 1 {
+		bitstring := Symbols2Bits(symbols, bits_per_symbol)
+		if len(bitstring) > bitstring_length {
+			bitstring = bitstring[:bitstring_length]
+		}
+		report.Bitstring = non_iid_estimates(bitstring, 2)
+		report.H_Bitstring = min(min_estimate(report.Bitstring), 1)
+		report.H_Final = min(report.H_Original, float64(bits_per_symbol)*report.H_Bitstring)
+	}
+	report.H_Final = max(0, report.H_Final)
+	return report
+}
+
+// IID熵源的最小熵估计(单位:比特/符号)
+func Estimate_IID(symbols []byte, alphabet_size int) float64 {
+	return MCV_Test(symbols)
+}
+
+// 非IID熵源的最小熵估计,取全部估计方法的最小值(单位:比特/符号)
+func Estimate_NonIID(symbols []byte, alphabet_size int) float64 {
+	return NonIID_Suite(symbols, max(1, bits.Len(uint(alphabet_size-1)))).H_Final
+}
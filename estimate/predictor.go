@@ -0,0 +1,226 @@
+package estimate
+
+var mcw_windows = []int{63, 255, 1023, 4095} //MultiMCW预测器的窗口大小
+
+const (
+	lag_depth          = 128    //Lag预测器的最大延迟
+	mmc_depth          = 16     //MultiMMC预测器的最大阶数
+	mmc_max_entries    = 100000 //MultiMMC预测器每阶字典的最大条目数
+	lz78y_depth        = 16     //LZ78Y预测器的最大上下文长度
+	lz78y_max_dict_len = 65536  //LZ78Y预测器字典的最大条目数
+)
+
+// 滑动窗口内的众数(出现次数相同时取最近出现者)
+type mcw_window struct {
+	Size       int      //窗口大小
+	Count      [256]int //窗口内各符号的出现次数
+	Last       [256]int //各符号最近一次出现的位置
+	Frequent   int      //窗口内的众数,-1表示尚无预测
+	Scoreboard int      //该窗口的正确预测次数
+}
+
+// 窗口加入新符号s(位于位置i),必要时移出最旧的符号
+func (window *mcw_window) Push(symbols []byte, i int) {
+	s := symbols[i]
+	window.Count[s]++
+	window.Last[s] = i
+	if window.Frequent == -1 || window.Count[s] >= window.Count[window.Frequent] {
+		window.Frequent = int(s)
+	}
+	if i < window.Size {
+		return
+	}
+	t := symbols[i-window.Size]
+	window.Count[t]--
+	if int(t) != window.Frequent {
+		return
+	}
+	for v := 0; v < 256; v++ {
+		if window.Count[v] == 0 {
+			continue
+		}
+		if window.Count[v] > window.Count[window.Frequent] || (window.Count[v] == window.Count[window.Frequent] && window.Last[v] > window.Last[window.Frequent]) {
+			window.Frequent = v
+		}
+	}
+}
+
+// MultiMCW预测器(SP 800-90B 6.3.7)
+func MultiMCW_Test(symbols []byte, alphabet_size int) Predictor_Result {
+	L := len(symbols)
+	w1 := mcw_windows[0]
+	if L <= w1 {
+		return Predictor_Estimate("MultiMCW", nil, alphabet_size)
+	}
+	windows := make([]*mcw_window, len(mcw_windows))
+	for j, size := range mcw_windows {
+		windows[j] = &mcw_window{Size: size, Frequent: -1}
+	}
+	correct := make([]bool, 0, L-w1)
+	winner := 0
+	for i := 0; i < L; i++ {
+		if i >= w1 {
+			prediction := windows[winner].Frequent
+			correct = append(correct, prediction == int(symbols[i]))
+			for j, window := range windows {
+				if i >= window.Size && window.Frequent == int(symbols[i]) {
+					window.Scoreboard++
+					if window.Scoreboard >= windows[winner].Scoreboard {
+						winner = j
+					}
+				}
+			}
+		}
+		for _, window := range windows {
+			window.Push(symbols, i)
+		}
+	}
+	return Predictor_Estimate("MultiMCW", correct, alphabet_size)
+}
+
+// Lag预测器(SP 800-90B 6.3.8)
+func Lag_Test(symbols []byte, alphabet_size int) Predictor_Result {
+	L := len(symbols)
+	if L < 2 {
+		return Predictor_Estimate("Lag", nil, alphabet_size)
+	}
+	scoreboard := make([]int, lag_depth+1)
+	correct := make([]bool, 0, L-1)
+	winner := 1
+	for i := 1; i < L; i++ {
+		prediction := -1
+		if winner <= i {
+			prediction = int(symbols[i-winner])
+		}
+		correct = append(correct, prediction == int(symbols[i]))
+		for d := 1; d <= lag_depth && d <= i; d++ {
+			if symbols[i-d] == symbols[i] {
+				scoreboard[d]++
+				if scoreboard[d] >= scoreboard[winner] {
+					winner = d
+				}
+			}
+		}
+	}
+	return Predictor_Estimate("Lag", correct, alphabet_size)
+}
+
+// 计数字典中出现次数最多的符号(次数相同时取较大的符号)
+func most_common(counts map[byte]int) (int, int) {
+	prediction := -1
+	max_count := 0
+	for y, c := range counts {
+		if c > max_count || (c == max_count && int(y) > prediction) {
+			prediction = int(y)
+			max_count = c
+		}
+	}
+	return prediction, max_count
+}
+
+// MultiMMC预测器(SP 800-90B 6.3.9)
+func MultiMMC_Test(symbols []byte, alphabet_size int) Predictor_Result {
+	L := len(symbols)
+	if L < 3 {
+		return Predictor_Estimate("MultiMMC", nil, alphabet_size)
+	}
+	M := make([]map[string]map[byte]int, mmc_depth+1)
+	entries := make([]int, mmc_depth+1)
+	for d := 1; d <= mmc_depth; d++ {
+		M[d] = make(map[string]map[byte]int)
+	}
+	scoreboard := make([]int, mmc_depth+1)
+	subpredict := make([]int, mmc_depth+1)
+	correct := make([]bool, 0, L-2)
+	winner := 1
+	for i := 2; i < L; i++ {
+		for d := 1; d <= mmc_depth && d < i; d++ {
+			context := string(symbols[i-d-1 : i-1])
+			y := symbols[i-1]
+			counts, ok := M[d][context]
+			if ok {
+				if _, ok := counts[y]; ok {
+					counts[y]++
+					continue
+				}
+			}
+			if entries[d] < mmc_max_entries {
+				if !ok {
+					counts = make(map[byte]int)
+					M[d][context] = counts
+				}
+				counts[y] = 1
+				entries[d]++
+			}
+		}
+		for d := 1; d <= mmc_depth; d++ {
+			subpredict[d] = -1
+			if d > i {
+				continue
+			}
+			if counts, ok := M[d][string(symbols[i-d:i])]; ok {
+				subpredict[d], _ = most_common(counts)
+			}
+		}
+		correct = append(correct, subpredict[winner] == int(symbols[i]))
+		for d := 1; d <= mmc_depth; d++ {
+			if subpredict[d] == int(symbols[i]) {
+				scoreboard[d]++
+				if scoreboard[d] >= scoreboard[winner] {
+					winner = d
+				}
+			}
+		}
+	}
+	return Predictor_Estimate("MultiMMC", correct, alphabet_size)
+}
+
+// LZ78Y预测器(SP 800-90B 6.3.10)
+func LZ78Y_Test(symbols []byte, alphabet_size int) Predictor_Result {
+	L := len(symbols)
+	B := lz78y_depth
+	if L < B+2 {
+		return Predictor_Estimate("LZ78Y", nil, alphabet_size)
+	}
+	D := make(map[string]map[byte]int)
+	correct := make([]bool, 0, L-B-1)
+	for i := B + 1; i < L; i++ {
+		for j := B; j >= 1; j-- {
+			context := string(symbols[i-j-1 : i-1])
+			counts, ok := D[context]
+			if !ok {
+				if len(D) >= lz78y_max_dict_len {
+					continue
+				}
+				counts = make(map[byte]int)
+				D[context] = counts
+			}
+			counts[symbols[i-1]]++
+		}
+		prediction := -1
+		max_count := 0
+		for j := B; j >= 1; j-- {
+			counts, ok := D[string(symbols[i-j:i])]
+			if !ok {
+				continue
+			}
+			y, c := most_common(counts)
+			if c > max_count {
+				prediction = y
+				max_count = c
+			}
+		}
+		correct = append(correct, prediction == int(symbols[i]))
+	}
+	return Predictor_Estimate("LZ78Y", correct, alphabet_size)
+}
+
+// 依次运行全部预测器
+func Predictor_Tests(symbols []byte, alphabet_size int) []Predictor_Result {
+	return []Predictor_Result{
+		MultiMCW_Test(symbols, alphabet_size),
+		Lag_Test(symbols, alphabet_size),
+		MultiMMC_Test(symbols, alphabet_size),
+		LZ78Y_Test(symbols, alphabet_size),
+	}
+}
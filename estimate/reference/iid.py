@@ -0,0 +1,258 @@
+#!/usr/bin/env python3
+"""SP 800-90B 5.1-5.2 IID检验统计量的独立参考实现,用于生成estimate包IID自检的期望结果.
+
+按SP 800-90B正文逐步实现(不参照Go代码),数据集与predictors.py相同:
+  置换检验的19个统计量: 只计算原始序列上的统计量T(不洗牌); 二元数据的方向游程、增减次数、
+      周期性与协方差使用转换I, 碰撞统计量使用转换II
+  压缩统计量: 调用系统libbz2的BZ2_bzBuffToBuffCompress(块大小5, 与NIST参考工具相同)
+  卡方独立性检验与拟合优度检验: 期望次数相同的分组按取值顺序分配; P值由正则化上不完全伽马函数计算
+  LRS检验: W由逐长度的子串集合求得
+
+用法: python3 iid.py            重新生成iid.txt
+      python3 iid.py --check    只比对iid.txt
+"""
+
+import ctypes
+import ctypes.util
+import math
+import os
+import sys
+
+from predictors import DATASETS, HERE, to_symbols
+
+LAGS = [1, 2, 8, 16, 32]
+
+
+def bzip2_length(text):
+    lib = ctypes.CDLL(ctypes.util.find_library("bz2") or "libbz2.so.1.0")
+    size = ctypes.c_uint(len(text) * 2 + 600)
+    dest = ctypes.create_string_buffer(size.value)
+    rc = lib.BZ2_bzBuffToBuffCompress(dest, ctypes.byref(size), text, len(text), 5, 0, 0)
+    assert rc == 0, rc
+    return size.value
+
+
+def conversion_1(S):
+    return [sum(S[i:i + 8]) for i in range(0, len(S) - 7, 8)]
+
+
+def conversion_2(S):
+    return [int("".join(map(str, S[i:i + 8])), 2) for i in range(0, len(S) - 7, 8)]
+
+
+def runs(signs):
+    lengths = []
+    for s in signs:
+        if lengths and s == lengths[-1][0]:
+            lengths[-1][1] += 1
+        else:
+            lengths.append([s, 1])
+    return len(lengths), max(length for _, length in lengths)
+
+
+def permutation_statistics(S, binary):
+    S1 = conversion_1(S) if binary else S
+    S2 = conversion_2(S) if binary else S
+    T = {}
+    mean = sum(S) / len(S)
+    T["excursion"] = excursion(S, mean)
+    signs = [S1[i] <= S1[i + 1] for i in range(len(S1) - 1)]
+    T["numDirectionalRuns"], T["lenDirectionalRuns"] = runs(signs)
+    T["numIncreasesDecreases"] = max(signs.count(True), signs.count(False))
+    median = 0.5 if binary else sorted_median(S)
+    T["numRunsMedian"], T["lenRunsMedian"] = runs([s >= median for s in S])
+    lengths, start = [], 0
+    while True:
+        seen, j = set(), start
+        while j < len(S2) and S2[j] not in seen:
+            seen.add(S2[j])
+            j += 1
+        if j == len(S2):
+            break
+        lengths.append(j - start + 1)
+        start = j + 1
+    T["avgCollision"] = sum(lengths) / len(lengths)
+    T["maxCollision"] = max(lengths)
+    for p in LAGS:
+        T[f"periodicity({p})"] = sum(S1[i] == S1[i + p] for i in range(len(S1) - p))
+    for p in LAGS:
+        T[f"covariance({p})"] = sum(S1[i] * S1[i + p] for i in range(len(S1) - p))
+    T["compression"] = bzip2_length(" ".join(map(str, S)).encode())
+    return T
+
+
+def excursion(S, mean):
+    total, best = 0, 0.0
+    for i, s in enumerate(S):
+        total += s
+        best = max(best, abs(total - (i + 1) * mean))
+    return best
+
+
+def sorted_median(S):
+    X = sorted(S)
+    L = len(X)
+    return X[L // 2] if L % 2 else (X[L // 2 - 1] + X[L // 2]) / 2
+
+
+def igamc(a, x):
+    """正则化上不完全伽马函数Q(a,x): x<a+1时用级数求P, 否则用Lentz连分式"""
+    if x <= 0:
+        return 1.0
+    log_front = a * math.log(x) - x - math.lgamma(a)
+    if x < a + 1:
+        term = total = 1 / a
+        n = a
+        while abs(term) > abs(total) * 1e-17:
+            n += 1
+            term *= x / n
+            total += term
+        return 1 - total * math.exp(log_front)
+    tiny = 1e-300
+    b = x + 1 - a
+    c = 1 / tiny
+    d = 1 / b
+    h = d
+    i = 1
+    while True:
+        an = -i * (i - a)
+        b += 2
+        d = an * d + b
+        d = tiny if abs(d) < tiny else d
+        c = b + an / c
+        c = tiny if abs(c) < tiny else c
+        d = 1 / d
+        delta = d * c
+        h *= delta
+        if abs(delta - 1) < 1e-17:
+            break
+        i += 1
+    return math.exp(log_front) * h
+
+
+def allocate_bins(expected):
+    """按期望次数从小到大(相同时按取值顺序)分组, 每组期望次数不小于5, 最后一组不足5时并入前一组"""
+    order = sorted((e, key) for key, e in expected.items() if e > 0)
+    bins, current, members = [], 0.0, []
+    for e, key in order:
+        current += e
+        members.append(key)
+        if current >= 5:
+            bins.append((current, members))
+            current, members = 0.0, []
+    if members:
+        if bins:
+            e, keys = bins.pop()
+            bins.append((e + current, keys + members))
+        else:
+            bins.append((current, members))
+    return bins
+
+
+def chi_square_independence(S, binary):
+    L = len(S)
+    if binary:
+        p1 = sum(S) / L
+        p0 = 1 - p1
+        m = 0
+        for j in range(2, 12):
+            if (L // j) * min(p0, p1) ** j >= 5:
+                m = j
+        if m < 2:
+            return 0.0, 0
+        observed = [0] * (1 << m)
+        for i in range(0, L - m + 1, m):
+            observed[int("".join(map(str, S[i:i + m])), 2)] += 1
+        T = 0.0
+        for value, o in enumerate(observed):
+            w = bin(value).count("1")
+            e = p1 ** w * p0 ** (m - w) * (L // m)
+            T += (o - e) ** 2 / e
+        return T, 2 ** m - 2
+    counts = {}
+    for s in S:
+        counts[s] = counts.get(s, 0) + 1
+    expected = {(a * 256 + b): counts[a] * counts[b] / L / L * (L - 1) for a in counts for b in counts}
+    bins = allocate_bins(expected)
+    bin_of = {key: index for index, (_, keys) in enumerate(bins) for key in keys}
+    observed = [0] * len(bins)
+    for i in range(L - 1):
+        observed[bin_of[S[i] * 256 + S[i + 1]]] += 1
+    T = sum((o - e) ** 2 / e for o, (e, _) in zip(observed, bins))
+    return T, len(bins) - len(counts)
+
+
+def chi_square_goodness_of_fit(S, binary):
+    part = len(S) // 10
+    counts = {}
+    for s in S[:part * 10]:
+        counts[s] = counts.get(s, 0) + 1
+    if binary:
+        bins = [(counts.get(0, 0) / 10, [0]), (counts.get(1, 0) / 10, [1])]
+    else:
+        bins = allocate_bins({s: c / 10 for s, c in counts.items()})
+    bin_of = {key: index for index, (_, keys) in enumerate(bins) for key in keys}
+    T = 0.0
+    for d in range(10):
+        observed = [0] * len(bins)
+        for s in S[d * part:(d + 1) * part]:
+            observed[bin_of[s]] += 1
+        T += sum((o - e) ** 2 / e for o, (e, _) in zip(observed, bins) if e > 0)
+    return T, 9 * (len(bins) - 1)
+
+
+def longest_repeated_substring(S):
+    data = bytes(S)
+    W = 0
+    while True:
+        seen = set()
+        length = W + 1
+        if any(data[i:i + length] in seen or seen.add(data[i:i + length]) for i in range(len(data) - length + 1)):
+            W = length
+        else:
+            return W
+
+
+def lrs(S):
+    L = len(S)
+    W = longest_repeated_substring(S)
+    p_col = sum((S.count(x) / L) ** 2 for x in set(S))
+    N = L - W + 1
+    P = -math.expm1(N * (N - 1) / 2 * math.log1p(-p_col ** W))
+    return W, p_col, P
+
+
+def reference_lines():
+    lines = []
+    for name, make in DATASETS:
+        data, bits_per_symbol = make()
+        S = to_symbols(data, bits_per_symbol)
+        binary = max(S) <= 1
+        for statistic, T in permutation_statistics(S, binary).items():
+            lines.append(f"{name} {bits_per_symbol} permutation {statistic} {T!r}")
+        for test, (T, df) in (("independence", chi_square_independence(S, binary)),
+                              ("goodnessOfFit", chi_square_goodness_of_fit(S, binary))):
+            P = igamc(df / 2, T / 2) if df >= 1 else 1.0
+            lines.append(f"{name} {bits_per_symbol} chiSquare {test} {T!r} {df} {P!r}")
+        W, p_col, P = lrs(S)
+        lines.append(f"{name} {bits_per_symbol} LRS {W} {p_col!r} {P!r}")
+    return lines
+
+
+def main():
+    path = os.path.join(HERE, "iid.txt")
+    header = "# dataset bits_per_symbol permutation name T | chiSquare name T df P | LRS W P_col P\n"
+    content = header + "\n".join(reference_lines()) + "\n"
+    if "--check" in sys.argv:
+        with open(path) as f:
+            if f.read() != content:
+                sys.exit("iid.txt mismatch")
+        print("iid.txt ok")
+        return
+    with open(path, "w") as f:
+        f.write(content)
+    print(content, end="")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,318 @@
+#!/usr/bin/env python3
+"""SP 800-90B 6.3.7-6.3.10 预测器估计的独立参考实现,用于生成estimate包的参考数据集与期望结果.
+
+按SP 800-90B正文逐步实现(不参照Go代码),平局规则采用NIST参考工具ea_non_iid的约定:
+  MultiMCW: 窗口内出现次数相同取最近出现者; 记分相同时切换到后面的预测器
+  Lag/MultiMMC: 记分相同时切换到后面的预测器
+  MultiMMC/LZ78Y: 计数相同时取值较大的符号; LZ78Y仅在计数严格大于maxcount时更新预测
+
+用法: python3 predictors.py            重新生成*.bin与reference.txt
+      python3 predictors.py --check    只比对reference.txt
+"""
+
+import hashlib
+import math
+import os
+import sys
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+Z_ALPHA = 2.5758293035489
+# 期望结果的来源,写入reference.txt的"# tool:"行; 改用NIST ea_non_iid的结果时应记录其版本
+TOOL = "predictors.py (independent implementation of SP 800-90B 6.3.7-6.3.10; NIST ea_non_iid not run)"
+
+
+def stream(label):
+    """以SHA-256计数器模式产生确定性的字节流"""
+    counter = 0
+    while True:
+        block = hashlib.sha256(label.encode() + counter.to_bytes(8, "big")).digest()
+        counter += 1
+        yield from block
+
+
+def uniform(gen):
+    """[0,1)上的均匀数,取4字节"""
+    return int.from_bytes(bytes(next(gen) for _ in range(4)), "big") / 2**32
+
+
+def dataset_biased_bits():
+    gen = stream("biased_bits")
+    bits = [1 if uniform(gen) < 0.7 else 0 for _ in range(80000)]
+    data = bytearray()
+    for i in range(0, len(bits), 8):
+        b = 0
+        for bit in bits[i:i + 8]:
+            b = b << 1 | bit
+        data.append(b)
+    return bytes(data), 1
+
+
+def dataset_markov_4():
+    gen = stream("markov_4")
+    s, data = 0, bytearray()
+    for _ in range(20000):
+        if uniform(gen) >= 0.6:
+            s = next(gen) % 4
+        data.append(s)
+    return bytes(data), 8
+
+
+def dataset_periodic_noise():
+    gen = stream("periodic_noise")
+    pattern = [0x17, 0x42, 0x42, 0x9c, 0x03, 0xe1, 0x5a]
+    data = bytearray()
+    for i in range(20000):
+        if uniform(gen) < 0.05:
+            data.append(next(gen))
+        else:
+            data.append(pattern[i % len(pattern)])
+    return bytes(data), 8
+
+
+def dataset_uniform_bytes():
+    gen = stream("uniform_bytes")
+    return bytes(next(gen) for _ in range(20000)), 8
+
+
+DATASETS = [
+    ("biased_bits", dataset_biased_bits),
+    ("markov_4", dataset_markov_4),
+    ("periodic_noise", dataset_periodic_noise),
+    ("uniform_bytes", dataset_uniform_bytes),
+]
+
+
+def to_symbols(data, bits_per_symbol):
+    if bits_per_symbol == 8:
+        return list(data)
+    symbols = []
+    for b in data:
+        for j in range(8 - bits_per_symbol, -1, -bits_per_symbol):
+            symbols.append(b >> j & (1 << bits_per_symbol) - 1)
+    return symbols
+
+
+def longest_run(correct):
+    best = run = 0
+    for c in correct:
+        run = run + 1 if c else 0
+        best = max(best, run)
+    return best
+
+
+def p_local(N, r):
+    """求解SP 800-90B 6.3.7步骤8(r为最长游程加1)使概率为0.99的p"""
+    def prob(p):
+        q = 1 - p
+        if q <= 0:
+            return 0.0
+        x = 1.0
+        for _ in range(10):
+            x = 1 + q * p**r * x**(r + 1)
+        num = 1 - p * x
+        den = (r + 1 - r * x) * q
+        if num <= 0 or den <= 0:
+            return 0.0
+        return math.exp(math.log(num) - math.log(den) - (N + 1) * math.log(x))
+
+    low, high = 0.0, 1.0
+    for _ in range(64):
+        mid = (low + high) / 2
+        if prob(mid) > 0.99:
+            low = mid
+        else:
+            high = mid
+    return high
+
+
+def min_entropy(correct, k):
+    N = len(correct)
+    C = sum(correct)
+    r = longest_run(correct) + 1
+    if C == 0:
+        pg = 1 - 0.01**(1 / N)
+    else:
+        p = C / N
+        pg = min(1.0, p + Z_ALPHA * math.sqrt(p * (1 - p) / (N - 1)))
+    pl = p_local(N, r)
+    return N, C, r, -math.log2(max(pg, pl, 1 / k))
+
+
+def multi_mcw(S):
+    W = [63, 255, 1023, 4095]
+    L = len(S)
+    scoreboard = [0] * len(W)
+    winner = 0
+    correct = []
+    for i in range(W[0], L):
+        frequent = []
+        for w in W:
+            if i < w:
+                frequent.append(None)
+                continue
+            window = S[i - w:i]
+            counts = {}
+            last = {}
+            for pos, s in enumerate(window):
+                counts[s] = counts.get(s, 0) + 1
+                last[s] = pos
+            frequent.append(max(counts, key=lambda s: (counts[s], last[s])))
+        correct.append(frequent[winner] == S[i])
+        for j in range(len(W)):
+            if frequent[j] == S[i]:
+                scoreboard[j] += 1
+                if scoreboard[j] >= scoreboard[winner]:
+                    winner = j
+    return correct
+
+
+def multi_mcw_fast(S):
+    """multi_mcw的增量版本(窗口计数随i滑动),结果与multi_mcw相同"""
+    W = [63, 255, 1023, 4095]
+    L = len(S)
+    counts = [[0] * 256 for _ in W]
+    last = [-1] * 256
+    scoreboard = [0] * len(W)
+    winner = 0
+    correct = []
+    for i in range(L):
+        if i >= W[0]:
+            frequent = []
+            for j, w in enumerate(W):
+                if i < w:
+                    frequent.append(None)
+                    continue
+                c = counts[j]
+                frequent.append(max((s for s in range(256) if c[s]), key=lambda s: (c[s], last[s])))
+            correct.append(frequent[winner] == S[i])
+            for j in range(len(W)):
+                if frequent[j] == S[i]:
+                    scoreboard[j] += 1
+                    if scoreboard[j] >= scoreboard[winner]:
+                        winner = j
+        for j, w in enumerate(W):
+            counts[j][S[i]] += 1
+            if i >= w:
+                counts[j][S[i - w]] -= 1
+        last[S[i]] = i
+    return correct
+
+
+def lag(S):
+    D = 128
+    scoreboard = [0] * (D + 1)
+    winner = 1
+    correct = []
+    for i in range(1, len(S)):
+        predictions = [None] + [S[i - d] if d <= i else None for d in range(1, D + 1)]
+        correct.append(predictions[winner] == S[i])
+        for d in range(1, D + 1):
+            if predictions[d] == S[i]:
+                scoreboard[d] += 1
+                if scoreboard[d] >= scoreboard[winner]:
+                    winner = d
+    return correct
+
+
+def most_common(counts):
+    return max(counts, key=lambda y: (counts[y], y))
+
+
+def multi_mmc(S):
+    D, MAX_ENTRIES = 16, 100000
+    M = [dict() for _ in range(D + 1)]
+    entries = [0] * (D + 1)
+    scoreboard = [0] * (D + 1)
+    winner = 1
+    correct = []
+    for i in range(2, len(S)):
+        for d in range(1, D + 1):
+            if d >= i:
+                continue
+            x, y = tuple(S[i - d - 1:i - 1]), S[i - 1]
+            if x in M[d] and y in M[d][x]:
+                M[d][x][y] += 1
+            elif entries[d] < MAX_ENTRIES:
+                M[d].setdefault(x, {})[y] = 1
+                entries[d] += 1
+        subpredict = [None] * (D + 1)
+        for d in range(1, D + 1):
+            if d > i:
+                continue
+            x = tuple(S[i - d:i])
+            if x in M[d]:
+                subpredict[d] = most_common(M[d][x])
+        correct.append(subpredict[winner] == S[i])
+        for d in range(1, D + 1):
+            if subpredict[d] == S[i]:
+                scoreboard[d] += 1
+                if scoreboard[d] >= scoreboard[winner]:
+                    winner = d
+    return correct
+
+
+def lz78y(S):
+    B, MAX_DICT = 16, 65536
+    Dict = {}
+    correct = []
+    for i in range(B + 1, len(S)):
+        for j in range(B, 0, -1):
+            x = tuple(S[i - j - 1:i - 1])
+            if x not in Dict and len(Dict) < MAX_DICT:
+                Dict[x] = {}
+            if x in Dict:
+                Dict[x][S[i - 1]] = Dict[x].get(S[i - 1], 0) + 1
+        prediction, maxcount = None, 0
+        for j in range(B, 0, -1):
+            prev = tuple(S[i - j:i])
+            if prev in Dict:
+                y = most_common(Dict[prev])
+                if Dict[prev][y] > maxcount:
+                    prediction, maxcount = y, Dict[prev][y]
+        correct.append(prediction == S[i])
+    return correct
+
+
+PREDICTORS = [("MultiMCW", multi_mcw_fast), ("Lag", lag), ("MultiMMC", multi_mmc), ("LZ78Y", lz78y)]
+
+
+def reference_lines(write):
+    lines = []
+    for name, make in DATASETS:
+        data, bits_per_symbol = make()
+        path = os.path.join(HERE, name + ".bin")
+        if write:
+            with open(path, "wb") as f:
+                f.write(data)
+        else:
+            with open(path, "rb") as f:
+                assert f.read() == data, name + ".bin mismatch"
+        S = to_symbols(data, bits_per_symbol)
+        k = len(set(S))
+        for predictor, run in PREDICTORS:
+            N, C, r, h = min_entropy(run(S), k)
+            lines.append(f"{name} {bits_per_symbol} {k} {predictor} {N} {C} {r} {h:.9f}")
+    return lines
+
+
+def main():
+    # 增量MultiMCW与逐窗口统计的直接实现交叉检查
+    S = to_symbols(dataset_markov_4()[0], 8)[:5000]
+    assert multi_mcw(S) == multi_mcw_fast(S)
+    lines = reference_lines("--check" not in sys.argv)
+    path = os.path.join(HERE, "reference.txt")
+    header = f"# tool: {TOOL}\n# dataset bits_per_symbol alphabet_size predictor N C R min_entropy\n"
+    content = header + "\n".join(lines) + "\n"
+    if "--check" in sys.argv:
+        with open(path) as f:
+            if f.read() != content:
+                sys.exit("reference.txt mismatch")
+        print("reference.txt ok")
+        return
+    with open(path, "w") as f:
+        f.write(content)
+    print(content, end="")
+
+
+if __name__ == "__main__":
+    main()
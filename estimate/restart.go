@@ -0,0 +1,154 @@
+package estimate
+
+import (
+	"fmt"
+	"math"
+	math_rand "math/rand/v2"
+	"slices"
+)
+
+const (
+	restart_rows    = 1000 //重启次数
+	restart_columns = 1000 //每次重启后采集的符号数
+)
+
+// 熵源构造函数:每次调用都重新创建熵源状态,返回该熵源的采样函数
+type Source_Constructor func() func() []byte
+
+// 熵估计函数,返回最小熵(单位:比特/符号)
+type Estimator func(symbols []byte, alphabet_size int) float64
+
+// 重启检验结果
+type Restart_Result struct {
+	H_I             float64 //初始熵估计
+	F_Max           int     //各行各列中最常见值出现次数的最大值
+	U               int     //健全性检查的临界值
+	Sanity_Pass     bool    //健全性检查是否通过
+	H_Row           float64 //行数据集的熵估计
+	H_Column        float64 //列数据集的熵估计
+	Validation_Pass bool    //熵估计验证是否通过
+	H_Final         float64 //重启检验后的熵估计
+	Pass            bool    //是否通过
+}
+
+// 采集重启矩阵:每行为一次重启后的前restart_columns个符号
+func Collect_Restart_Matrix(constructor Source_Constructor, bits_per_symbol int) [][]byte {
+	matrix := make([][]byte, restart_rows)
+	for r := range matrix {
+		get_source := constructor()
+		row := make([]byte, 0, restart_columns)
+		for len(row) < restart_columns {
+			row = append(row, Bytes2Symbols(get_source(), bits_per_symbol)...)
+		}
+		matrix[r] = row[:restart_columns]
+	}
+	return matrix
+}
+
+// 行数据集与列数据集
+func Restart_Datasets(matrix [][]byte) ([]byte, []byte) {
+	rows := slices.Concat(matrix...)
+	columns := make([]byte, 0, len(rows))
+	for c := 0; c < len(matrix[0]); c++ {
+		for r := range matrix {
+			columns = append(columns, matrix[r][c])
+		}
+	}
+	return rows, columns
+}
+
+// 最常见值的出现次数
+func most_common_count(symbols []byte) int {
+	var counts [256]int
+	for _, s := range symbols {
+		counts[s]++
+	}
+	return slices.Max(counts[:])
+}
+
+// 二项分布X~B(n,p)上尾概率P(X>U)<=alpha的最小整数U,由上尾逐项累加精确计算
+func binomial_critical_value(n int, p float64, alpha float64) int {
+	if p >= 1 {
+		return n
+	}
+	if p <= 0 {
+		return 0
+	}
+	log_p, log_q := math.Log(p), math.Log1p(-p)
+	lgamma_n, _ := math.Lgamma(float64(n + 1))
+	tail := 0.0 //P(X>U)
+	for U := n; U > 0; U-- {
+		lgamma_U, _ := math.Lgamma(float64(U + 1))
+		lgamma_rest, _ := math.Lgamma(float64(n - U + 1))
+		tail += math.Exp(lgamma_n - lgamma_U - lgamma_rest + float64(U)*log_p + float64(n-U)*log_q)
+		if tail > alpha {
+			return U
+		}
+	}
+	return 0
+}
+
+// 健全性检查(SP 800-90B 3.1.4.3):各行各列中最常见值的出现次数F_max不应超过临界值U,
+// U为X~B(n,2^-H_I)满足P(X>U)<=alpha的最小整数,alpha=0.01/(2·k·行数)
+func Restart_Sanity_Check(matrix [][]byte, H_I float64, alphabet_size int) (int, int, bool) {
+	F_max := 0
+	for _, row := range matrix {
+		F_max = max(F_max, most_common_count(row))
+	}
+	column := make([]byte, len(matrix))
+	for c := 0; c < len(matrix[0]); c++ {
+		for r := range matrix {
+			column[r] = matrix[r][c]
+		}
+		F_max = max(F_max, most_common_count(column))
+	}
+	alpha := 0.01 / float64(alphabet_size*2*len(matrix))
+	U := binomial_critical_value(len(matrix[0]), math.Pow(2, -H_I), alpha)
+	return F_max, U, F_max <= U
+}
+
+// 重启检验(SP 800-90B 3.1.4)
+func Restart_Test(matrix [][]byte, H_I float64, alphabet_size int, estimator Estimator) Restart_Result {
+	result := Restart_Result{H_I: H_I, H_Final: H_I}
+	result.F_Max, result.U, result.Sanity_Pass = Restart_Sanity_Check(matrix, H_I, alphabet_size)
+	rows, columns := Restart_Datasets(matrix)
+	result.H_Row = estimator(rows, alphabet_size)
+	result.H_Column = estimator(columns, alphabet_size)
+	result.Validation_Pass = min(result.H_Row, result.H_Column) >= H_I/2
+	result.Pass = result.Sanity_Pass && result.Validation_Pass
+	if result.Pass {
+		result.H_Final = min(H_I, result.H_Row, result.H_Column)
+	}
+	return result
+}
+
+// 重启检验驱动:反复调用熵源构造函数采集重启矩阵并完成检验
+func Restart_Driver(constructor Source_Constructor, bits_per_symbol int, H_I float64, is_iid bool) Restart_Result {
+	matrix := Collect_Restart_Matrix(constructor, bits_per_symbol)
+	alphabet_size := 1 << bits_per_symbol
+	estimator := Estimate_NonIID
+	if is_iid {
+		estimator = Estimate_IID
+	}
+	result := Restart_Test(matrix, H_I, alphabet_size, estimator)
+	if !result.Pass {
+		fmt.Println("重启检验未通过!")
+	}
+	return result
+}
+
+// 模拟熵源构造函数:每次重启使用不同的种子,以概率bias重复上一个符号,用于持续集成
+func Simulated_Constructor(seed uint64, bias float64) Source_Constructor {
+	restarts := uint64(0)
+	return func() func() []byte {
+		restarts++
+		random := math_rand.New(math_rand.NewPCG(seed, restarts))
+		last := byte(random.Uint32())
+		return func() []byte {
+			if random.Float64() >= bias {
+				last = byte(random.Uint32())
+			}
+			return []byte{last}
+		}
+	}
+}
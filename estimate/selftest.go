@@ -0,0 +1,256 @@
+package estimate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"embed"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 参考数据集与期望结果,由reference/predictors.py(按SP 800-90B正文独立实现的预测器)
+// 与reference/iid.py(IID检验统计量,压缩统计量调用系统libbz2)生成
+//
+//go:embed reference/*.bin reference/reference.txt reference/iid.txt
+var reference_files embed.FS
+
+const reference_tolerance = 1e-6 //最小熵期望值的允许误差
+
+// 预测器参考数据集
+type Reference_Dataset struct {
+	Name            string             //数据集名称
+	Data            []byte             //原始样本
+	Bits_Per_Symbol int                //每个符号的比特数
+	Alphabet_Size   int                //字母表大小(出现过的符号个数)
+	Expected        []Predictor_Result //各预测器的期望结果(N、C、R与Min_Entropy)
+	Tool            string             //期望结果的来源(生成工具及版本)
+}
+
+// 读取内置的参考数据集
+func Reference_Datasets() ([]Reference_Dataset, error) {
+	content, err := reference_files.ReadFile("reference/reference.txt")
+	if err != nil {
+		return nil, err
+	}
+	var datasets []Reference_Dataset
+	tool := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, ok := strings.CutPrefix(line, "# tool:"); ok {
+			tool = strings.TrimSpace(value)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var dataset Reference_Dataset
+		var expected Predictor_Result
+		if _, err := fmt.Sscan(line, &dataset.Name, &dataset.Bits_Per_Symbol, &dataset.Alphabet_Size, &expected.Name, &expected.N, &expected.C, &expected.R, &expected.Min_Entropy); err != nil {
+			return nil, fmt.Errorf("Reference_Datasets error: %q: %w", line, err)
+		}
+		expected.Applicable = true
+		if len(datasets) == 0 || datasets[len(datasets)-1].Name != dataset.Name {
+			dataset.Tool = tool
+			dataset.Data, err = reference_files.ReadFile("reference/" + dataset.Name + ".bin")
+			if err != nil {
+				return nil, err
+			}
+			datasets = append(datasets, dataset)
+		}
+		last := &datasets[len(datasets)-1]
+		last.Expected = append(last.Expected, expected)
+	}
+	return datasets, nil
+}
+
+// 预测器自检:对参考数据集运行Predictor_Tests,N、C、R应与期望值一致,最小熵误差不超过reference_tolerance;
+// 预测次数不足2的预测器应标记为不适用
+func Test_Predictors() int {
+	for _, result := range Predictor_Tests([]byte{0, 1, 0, 1, 1}, 2) {
+		if result.Applicable != (result.N >= 2) || (!result.Applicable && result.Min_Entropy != 0) {
+			fmt.Println("Test_Predictors error: 短序列", result.Name)
+			return -1
+		}
+	}
+	datasets, err := Reference_Datasets()
+	if err != nil {
+		fmt.Println(err)
+		return -1
+	}
+	for _, dataset := range datasets {
+		symbols := Bytes2Symbols(dataset.Data, dataset.Bits_Per_Symbol)
+		results := Predictor_Tests(symbols, dataset.Alphabet_Size)
+		if len(results) != len(dataset.Expected) {
+			fmt.Println("Test_Predictors error:", dataset.Name)
+			return -1
+		}
+		for i, result := range results {
+			expected := dataset.Expected[i]
+			if result.Name != expected.Name || !result.Applicable || result.N != expected.N || result.C != expected.C || result.R != expected.R ||
+				math.Abs(result.Min_Entropy-expected.Min_Entropy) > reference_tolerance {
+				fmt.Printf("Test_Predictors error: %s %s: 期望N=%d C=%d R=%d H=%.9f,实际N=%d C=%d R=%d H=%.9f\n", dataset.Name, expected.Name,
+					expected.N, expected.C, expected.R, expected.Min_Entropy, result.N, result.C, result.R, result.Min_Entropy)
+				return -1
+			}
+		}
+	}
+	return 0
+}
+
+// 重启检验自检:均匀分布的模拟熵源(8比特符号,声明H_I=8)经Restart_Driver应通过健全性检查与验证,
+// 临界值应为精确二项分布的19;每次重启输出恒定符号的模拟熵源应不通过健全性检查
+func Test_Restart() int {
+	result := Restart_Driver(Simulated_Constructor(1, 0), 8, 8, true)
+	if !result.Sanity_Pass || !result.Pass || result.U != 19 || result.H_Final <= 4 || result.H_Final > 8 {
+		fmt.Printf("Test_Restart error: F_max=%d U=%d H_Row=%.4f H_Column=%.4f\n", result.F_Max, result.U, result.H_Row, result.H_Column)
+		return -1
+	}
+	result = Restart_Driver(Simulated_Constructor(1, 1), 8, 8, true)
+	if result.Sanity_Pass || result.Pass {
+		fmt.Println("Test_Restart error: 恒定熵源通过了健全性检查")
+		return -1
+	}
+	return 0
+}
+
+// 相对误差不超过reference_tolerance(期望值接近0时取绝对误差)
+func close_to(actual float64, expected float64) bool {
+	return math.Abs(actual-expected) <= reference_tolerance*max(1, math.Abs(expected))
+}
+
+// SP 800-90B 5.1节的计算示例
+func test_permutation_examples() bool {
+	signs := make([]bool, 0, 16)
+	T_excursion := excursion([]byte{2, 15, 4, 10, 9}, 8)
+	number, longest, increases := directional_runs([]byte{2, 2, 2, 5, 7, 7, 9, 3, 1, 4, 4}, signs)
+	median_number, median_longest := median_runs([]byte{5, 15, 12, 1, 13, 9, 4}, 9, signs)
+	average, maximum := collision([]byte{2, 1, 1, 2, 0, 1, 0, 1, 1, 2})
+	periodicity, _ := periodicity_covariance([]byte{2, 1, 2, 1, 0, 1, 0, 1, 1, 2}, 2)
+	_, covariance := periodicity_covariance([]byte{5, 2, 6, 10, 12, 3, 1}, 2)
+	return T_excursion == 6 && number == 3 && longest == 6 && increases == 8 &&
+		median_number == 5 && median_longest == 2 && average == 3 && maximum == 4 &&
+		periodicity == 5 && covariance == 164
+}
+
+// bzip2压缩自检:空输入为14字节(流头与流尾),跨越多个块的输入经compress/bzip2解压后应与原文一致
+func test_bzip2() bool {
+	var encoder bzip2_encoder
+	if len(encoder.compress(nil)) != 14 {
+		return false
+	}
+	text := make([]byte, 0, 3*bzip2_block_size*100000)
+	for i := 0; len(text) < cap(text); i++ {
+		text = strconv.AppendInt(text, int64(i*i%1009), 10)
+		text = append(text, ' ')
+		if i%7 == 0 {
+			text = append(text, bytes.Repeat([]byte{'7'}, i%300)...)
+		}
+	}
+	decompressed, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(encoder.compress(text))))
+	return err == nil && bytes.Equal(decompressed, text)
+}
+
+// IID检验自检:置换检验统计量与SP 800-90B 5.1节的计算示例一致,bzip2压缩可被正确解压;
+// 在参考数据集上,19个置换检验统计量、卡方检验(T、自由度与P值)与LRS检验的结果与reference/iid.py一致,
+// 其中压缩统计量的期望值为libbz2的压缩长度
+func Test_IID() int {
+	if !test_permutation_examples() {
+		fmt.Println("Test_IID error: SP 800-90B计算示例")
+		return -1
+	}
+	if !test_bzip2() {
+		fmt.Println("Test_IID error: bzip2")
+		return -1
+	}
+	datasets, err := Reference_Datasets()
+	if err != nil {
+		fmt.Println(err)
+		return -1
+	}
+	content, err := reference_files.ReadFile("reference/iid.txt")
+	if err != nil {
+		fmt.Println(err)
+		return -1
+	}
+	type computed struct {
+		permutation map[string]float64
+		chi_square  map[string]Chi_Square_Result
+		lrs         LRS_Result
+	}
+	results := map[string]*computed{}
+	for _, dataset := range datasets {
+		symbols := Bytes2Symbols(dataset.Data, dataset.Bits_Per_Symbol)
+		is_binary := Is_Binary(symbols)
+		median := Median(symbols)
+		if is_binary {
+			median = 0.5
+		}
+		mean := 0.0
+		for _, s := range symbols {
+			mean += float64(s)
+		}
+		mean /= float64(len(symbols))
+		T := permutation_statistics(symbols, is_binary, median, mean, &permutation_scratch{})
+		result := &computed{permutation: map[string]float64{}, chi_square: map[string]Chi_Square_Result{}, lrs: LRS_Test(symbols)}
+		for i, name := range Permutation_Names {
+			result.permutation[name] = T[i]
+		}
+		for _, r := range []Chi_Square_Result{Chi_Square_Independence(symbols), Chi_Square_Goodness_Of_Fit(symbols)} {
+			result.chi_square[r.Name] = r
+		}
+		results[dataset.Name] = result
+	}
+	checked := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		result, ok := results[fields[0]]
+		if !ok || len(fields) < 5 {
+			fmt.Println("Test_IID error:", line)
+			return -1
+		}
+		number := func(i int) float64 {
+			if i >= len(fields) {
+				return math.NaN()
+			}
+			v, _ := strconv.ParseFloat(fields[i], 64)
+			return v
+		}
+		switch fields[2] {
+		case "permutation":
+			T, ok := result.permutation[fields[3]]
+			if !ok || !close_to(T, number(4)) {
+				fmt.Printf("Test_IID error: %s %s: 期望%v,实际%v\n", fields[0], fields[3], fields[4], T)
+				return -1
+			}
+		case "chiSquare":
+			r, ok := result.chi_square[fields[3]]
+			if !ok || !close_to(r.T, number(4)) || float64(r.Df) != number(5) || !close_to(r.P_Value, number(6)) {
+				fmt.Printf("Test_IID error: %s %s: 期望T=%s df=%s P=%s,实际T=%v df=%d P=%v\n", fields[0], fields[3], fields[4], fields[5], fields[6], r.T, r.Df, r.P_Value)
+				return -1
+			}
+		case "LRS":
+			r := result.lrs
+			if float64(r.W) != number(3) || !close_to(r.P_Col, number(4)) || !close_to(r.P, number(5)) {
+				fmt.Printf("Test_IID error: %s LRS: 期望W=%s P_col=%s P=%s,实际W=%d P_col=%v P=%v\n", fields[0], fields[3], fields[4], fields[5], r.W, r.P_Col, r.P)
+				return -1
+			}
+		default:
+			fmt.Println("Test_IID error:", line)
+			return -1
+		}
+		checked++
+	}
+	if checked != len(datasets)*(len(Permutation_Names)+3) {
+		fmt.Println("Test_IID error: 参考结果不完整")
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package estimate
+
+// 后缀数组(倍增法,每轮按(rank[i],rank[i+k])基数排序),时间复杂度O(L log L)
+func suffix_array(symbols []byte) []int {
+	n := len(symbols)
+	sa := make([]int, n)
+	second := make([]int, n)
+	rank := make([]int, n)
+	next_rank := make([]int, n)
+	counts := make([]int, max(n, 256)+1)
+	for i, s := range symbols {
+		rank[i] = int(s)
+	}
+	//按rank对order稳定排序,结果写入sa
+	counting_sort := func(order []int, classes int) {
+		clear(counts[:classes+1])
+		for _, i := range order {
+			counts[rank[i]+1]++
+		}
+		for c := 1; c <= classes; c++ {
+			counts[c] += counts[c-1]
+		}
+		for _, i := range order {
+			sa[counts[rank[i]]] = i
+			counts[rank[i]]++
+		}
+	}
+	for i := range second {
+		second[i] = i
+	}
+	classes := 256
+	counting_sort(second, classes)
+	for k := 1; ; k <<= 1 {
+		//按第二关键字rank[i+k]排序:i+k越界的后缀最小
+		p := 0
+		for i := n - k; i < n; i++ {
+			if i >= 0 {
+				second[p] = i
+				p++
+			}
+		}
+		for _, i := range sa {
+			if i >= k {
+				second[p] = i - k
+				p++
+			}
+		}
+		counting_sort(second, classes)
+		key := func(i int) int {
+			if i+k < n {
+				return rank[i+k]
+			}
+			return -1
+		}
+		next_rank[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			a, b := sa[j-1], sa[j]
+			next_rank[b] = next_rank[a]
+			if rank[a] != rank[b] || key(a) != key(b) {
+				next_rank[b]++
+			}
+		}
+		rank, next_rank = next_rank, rank
+		classes = rank[sa[n-1]] + 1
+		if classes == n {
+			return sa
+		}
+	}
+}
+
+// LCP数组(Kasai算法):lcp[i]为后缀sa[i-1]与sa[i]的最长公共前缀长度,lcp[0]=0
+func lcp_array(symbols []byte, sa []int) []int {
+	n := len(symbols)
+	rank := make([]int, n)
+	for i, s := range sa {
+		rank[s] = i
+	}
+	lcp := make([]int, n)
+	h := 0
+	for i := 0; i < n; i++ {
+		if rank[i] == 0 {
+			h = 0
+			continue
+		}
+		j := sa[rank[i]-1]
+		for i+h < n && j+h < n && symbols[i+h] == symbols[j+h] {
+			h++
+		}
+		lcp[rank[i]] = h
+		if h > 0 {
+			h--
+		}
+	}
+	return lcp
+}
+
+// 元组统计:Q[t]为出现次数最多的t元组的出现次数,pairs[t]为相同t元组的对数(各t元组的C(c,2)之和),
+// longest为最长重复子串的长度。由LCP区间一次遍历得到,与NIST SP 800-90B参考实现一致
+func tuple_statistics(symbols []byte) ([]int, []int, int) {
+	n := len(symbols)
+	Q := make([]int, n+1)
+	pairs := make([]int, n+2)
+	if n < 2 {
+		return Q, pairs, 0
+	}
+	lcp := lcp_array(symbols, suffix_array(symbols))
+	longest := 0
+	//LCP区间:区间内后缀的公共前缀长度为value,起点为left
+	type interval struct{ value, left int }
+	stack := []interval{{0, 0}}
+	for i := 1; i <= n; i++ {
+		h := 0
+		if i < n {
+			h = lcp[i]
+			longest = max(longest, h)
+		}
+		left := i - 1
+		for h < stack[len(stack)-1].value {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			left = top.left
+			//长度在(parent,top.value]内的元组在该区间内各出现width次
+			width := i - top.left
+			parent := max(h, stack[len(stack)-1].value)
+			Q[top.value] = max(Q[top.value], width)
+			pairs[parent+1] += width * (width - 1) / 2
+			pairs[top.value+1] -= width * (width - 1) / 2
+		}
+		if h > stack[len(stack)-1].value {
+			stack = append(stack, interval{h, left})
+		}
+	}
+	for t := n - 1; t >= 1; t-- {
+		Q[t] = max(Q[t], Q[t+1])
+	}
+	for t := 1; t <= n; t++ {
+		pairs[t] += pairs[t-1]
+	}
+	return Q, pairs[:n+1], longest
+}
@@ -0,0 +1,207 @@
+// generate以DRBG输出生成UUID、令牌、口令与一次性口令,并给出每个秘密的熵
+package generate
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jellygdh/drbg_sm3/random"
+)
+
+// 生成的秘密
+type Secret struct {
+	Value   string  //秘密的文本
+	Entropy float64 //熵(单位:比特),即log2(可能取值的个数),各取值等概率
+}
+
+// 令牌编码
+type Token_Encoding int
+
+const (
+	Base64URL Token_Encoding = iota //URL安全的base64,无填充
+	Base32                          //base32(RFC 4648),无填充
+	Hex                             //小写十六进制
+)
+
+// 口令字符类
+const (
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits    = "0123456789"
+	Symbols   = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+const max_otp_digits = 19 //一次性口令的最大位数,10^19<2^64
+
+// 读取n字节随机数
+func read_bytes(generator *random.Generator, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := generator.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UUID的文本形式
+func uuid_string(u []byte) string {
+	h := hex.EncodeToString(u)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// 版本4 UUID(RFC 9562):122比特随机数
+func UUID_V4(generator *random.Generator) (Secret, error) {
+	u, err := read_bytes(generator, 16)
+	if err != nil {
+		return Secret{}, err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return Secret{uuid_string(u), 122}, nil
+}
+
+// 版本7 UUID(RFC 9562):48比特毫秒级Unix时间戳加74比特随机数,按时间排序。
+// 时间戳可预测,不计入熵
+func UUID_V7(generator *random.Generator, t time.Time) (Secret, error) {
+	u, err := read_bytes(generator, 16)
+	if err != nil {
+		return Secret{}, err
+	}
+	milliseconds := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		u[i] = byte(milliseconds >> (40 - 8*i))
+	}
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+	return Secret{uuid_string(u), 74}, nil
+}
+
+// 熵不少于entropy_bits比特的令牌,随机字节数向上取整
+func Token(generator *random.Generator, entropy_bits int, encoding Token_Encoding) (Secret, error) {
+	if entropy_bits <= 0 {
+		return Secret{}, errors.New("Token error: entropy must be positive")
+	}
+	b, err := read_bytes(generator, (entropy_bits+7)/8)
+	if err != nil {
+		return Secret{}, err
+	}
+	var value string
+	switch encoding {
+	case Base64URL:
+		value = base64.RawURLEncoding.EncodeToString(b)
+	case Base32:
+		value = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	case Hex:
+		value = hex.EncodeToString(b)
+	default:
+		return Secret{}, errors.New("Token error: unknown encoding")
+	}
+	return Secret{value, float64(len(b) * 8)}, nil
+}
+
+// 检查字符类非空且互不相交,返回合并后的字母表
+func password_alphabet(classes []string) ([]rune, error) {
+	if len(classes) == 0 {
+		return nil, errors.New("Password error: no character classes")
+	}
+	seen := make(map[rune]bool)
+	var alphabet []rune
+	for _, class := range classes {
+		if class == "" {
+			return nil, errors.New("Password error: empty character class")
+		}
+		for _, r := range class {
+			if seen[r] {
+				return nil, fmt.Errorf("Password error: duplicate character %q", r)
+			}
+			seen[r] = true
+			alphabet = append(alphabet, r)
+		}
+	}
+	return alphabet, nil
+}
+
+// 长度为length、每个字符类至少出现一次的字符串个数(容斥原理):
+// Σ_{S⊆classes} (-1)^|S| (N-Σ_{c∈S}|c|)^length
+func password_count(classes []string, alphabet_size int, length int) *big.Int {
+	count := new(big.Int)
+	term := new(big.Int)
+	for subset := 0; subset < 1<<len(classes); subset++ {
+		remaining := alphabet_size
+		sign := 1
+		for i, class := range classes {
+			if subset&(1<<i) != 0 {
+				remaining -= len([]rune(class))
+				sign = -sign
+			}
+		}
+		term.Exp(big.NewInt(int64(remaining)), big.NewInt(int64(length)), nil)
+		if sign > 0 {
+			count.Add(count, term)
+		} else {
+			count.Sub(count, term)
+		}
+	}
+	return count
+}
+
+// log2(n),n>0
+func log2_big(n *big.Int) float64 {
+	shift := max(n.BitLen()-53, 0)
+	mantissa, _ := new(big.Int).Rsh(n, uint(shift)).Float64()
+	return math.Log2(mantissa) + float64(shift)
+}
+
+// 由互不相交的字符类组成、长度为length的口令,每个字符类至少出现一次。
+// 从合并字母表中逐字符无偏选取,不满足覆盖要求时整体重取,因此口令在全部满足要求的字符串上均匀分布
+func Password(generator *random.Generator, length int, classes ...string) (Secret, error) {
+	alphabet, err := password_alphabet(classes)
+	if err != nil {
+		return Secret{}, err
+	}
+	if length < len(classes) {
+		return Secret{}, errors.New("Password error: length shorter than number of classes")
+	}
+	password := make([]rune, length)
+	for {
+		for i := range password {
+			password[i] = alphabet[generator.Uint64n(uint64(len(alphabet)))]
+		}
+		if generator.Err != nil {
+			return Secret{}, generator.Err
+		}
+		covered := true
+		for _, class := range classes {
+			if !strings.ContainsAny(string(password), class) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			break
+		}
+	}
+	return Secret{string(password), log2_big(password_count(classes, len(alphabet), length))}, nil
+}
+
+// digits位数字一次性口令,不足位数时左侧补0
+func OTP(generator *random.Generator, digits int) (Secret, error) {
+	if digits <= 0 || digits > max_otp_digits {
+		return Secret{}, fmt.Errorf("OTP error: digits must be in [1,%d]", max_otp_digits)
+	}
+	n := uint64(1)
+	for i := 0; i < digits; i++ {
+		n *= 10
+	}
+	value := generator.Uint64n(n)
+	if generator.Err != nil {
+		return Secret{}, generator.Err
+	}
+	return Secret{fmt.Sprintf("%0*d", digits, value), float64(digits) * math.Log2(10)}, nil
+}
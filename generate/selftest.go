@@ -0,0 +1,62 @@
+package generate
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+	"github.com/jellygdh/drbg_sm3/random"
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+// 自检:以固定种子的SM3 Hash_DRBG为随机源,检查UUID的版本与变体、令牌长度、一次性口令格式,
+// 并以两个字符类("ab"与"01")的3字符口令检查覆盖要求、熵(满足要求的48种取值)与均匀性(卡方检验)
+func Test_Generate() int {
+	seed := []byte("generate self-test")
+	generator := random.New(bufio.NewReader(drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil)))
+
+	v4, err := UUID_V4(generator)
+	if err != nil || len(v4.Value) != 36 || v4.Value[14] != '4' || !strings.ContainsRune("89ab", rune(v4.Value[19])) {
+		return -1
+	}
+	t := time.UnixMilli(0x0123456789ab)
+	v7, err := UUID_V7(generator, t)
+	if err != nil || !strings.HasPrefix(v7.Value, "01234567-89ab-7") || !strings.ContainsRune("89ab", rune(v7.Value[19])) {
+		return -1
+	}
+	for encoding, length := range map[Token_Encoding]int{Base64URL: 22, Base32: 26, Hex: 32} {
+		token, err := Token(generator, 128, encoding)
+		if err != nil || len(token.Value) != length || token.Entropy != 128 {
+			return -1
+		}
+	}
+	otp, err := OTP(generator, 6)
+	if err != nil || len(otp.Value) != 6 || strings.Trim(otp.Value, Digits) != "" {
+		return -1
+	}
+
+	const samples_per_value = 200
+	observed := make(map[string]int)
+	entropy := 0.0
+	for i := 0; i < 48*samples_per_value; i++ {
+		password, err := Password(generator, 3, "ab", "01")
+		if err != nil || !strings.ContainsAny(password.Value, "ab") || !strings.ContainsAny(password.Value, "01") {
+			return -1
+		}
+		observed[password.Value]++
+		entropy = password.Entropy
+	}
+	if len(observed) != 48 || math.Abs(entropy-math.Log2(48)) > 1e-9 {
+		return -1
+	}
+	X := 0.0
+	for _, o := range observed {
+		X += (float64(o) - samples_per_value) * (float64(o) - samples_per_value) / samples_per_value
+	}
+	if tools.Igamc(float64(48-1)/2, X/2) < 0.001 {
+		return -1
+	}
+	return 0
+}
@@ -100,6 +100,60 @@ func Get_EntropySource() []byte {
 	return bytes
 }
 
+// 熵源描述结构体
+type Entropy_Source struct {
+	Name   string        //熵源名称
+	Length int           //单次采样的字节数
+	Get    func() []byte //采样函数
+}
+
+// 已注册的熵源,顺序与健康测试变量B的下标一致
+var Entropy_Sources = []Entropy_Source{
+	{"timestamp", 4, Get_Timestamp},
+	{"cpu", 12, Get_CPU},
+	{"mem", 8, Get_Mem},
+	{"disk", 16, Get_Disk},
+	{"net", 8, Get_Net},
+	{"system_random", 4, Get_SystemRandom},
+	{"hardware_random", 4, Get_HardwareRandom},
+}
+
+// 按名称查找熵源,返回其在Entropy_Sources中的下标,未找到时返回-1
+func Find_Source(name string) int {
+	for i, source := range Entropy_Sources {
+		if source.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// 熵源重启:清除该熵源的健康测试状态,返回其采样函数
+func Restart_Source(name string) func() []byte {
+	n := Find_Source(name)
+	switch n {
+	case 0:
+		Last_Timestamp = nil
+	case 1:
+		Last_CPU = nil
+	case 2:
+		Last_Mem = nil
+	case 3:
+		Last_Disk = nil
+	case 4:
+		Last_Net = nil
+	case 5:
+		Last_SystemRandom = nil
+	case 6:
+		Last_HardwareRandom = nil
+	default:
+		fmt.Println("Restart_Source error!")
+		return nil
+	}
+	B[n] = 0
+	return Entropy_Sources[n].Get
+}
+
 // 熵池初始化
 func (working_state *Working_State) Init() {
 	working_state.Pool_Content = make([]byte, Pool_Capacity)
@@ -0,0 +1,189 @@
+// random在DRBG输出之上提供无偏的随机整数、区间、big.Int、浮点数、排列与抽样
+package random
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+)
+
+// 随机数生成器,从reader(通常为DRBG)读取随机字节。读取失败时记录在Err中,此后各函数返回零值
+type Generator struct {
+	reader io.Reader
+	Err    error //首次读取错误
+}
+
+// 以reader为随机源创建生成器
+func New(reader io.Reader) *Generator {
+	return &Generator{reader: reader}
+}
+
+// 以SM3 DRBG为随机源创建生成器
+func New_SM3(Mode int, personalization_string string) *Generator {
+	return New(drbg.Init_DRBG_SM3(Mode, personalization_string))
+}
+
+// 读取len(p)字节
+func (generator *Generator) read(p []byte) bool {
+	if generator.Err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(generator.reader, p); err != nil {
+		generator.Err = err
+		return false
+	}
+	return true
+}
+
+// 实现io.Reader,读取失败后始终返回首次读取错误
+func (generator *Generator) Read(p []byte) (int, error) {
+	if !generator.read(p) {
+		return 0, generator.Err
+	}
+	return len(p), nil
+}
+
+// 均匀分布的32比特无符号整数
+func (generator *Generator) Uint32() uint32 {
+	var b [4]byte
+	if !generator.read(b[:]) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// 均匀分布的64比特无符号整数
+func (generator *Generator) Uint64() uint64 {
+	var b [8]byte
+	if !generator.read(b[:]) {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// [0,n)上均匀分布的整数,n为0时返回0。
+// 拒绝采样:丢弃小于2^32 mod n的值,使剩余值的个数为n的整数倍,再取模
+func (generator *Generator) Uint32n(n uint32) uint32 {
+	if n == 0 {
+		return 0
+	}
+	threshold := -n % n
+	for {
+		x := generator.Uint32()
+		if generator.Err != nil {
+			return 0
+		}
+		if x >= threshold {
+			return x % n
+		}
+	}
+}
+
+// [0,n)上均匀分布的整数,n为0时返回0
+func (generator *Generator) Uint64n(n uint64) uint64 {
+	if n == 0 {
+		return 0
+	}
+	threshold := -n % n
+	for {
+		x := generator.Uint64()
+		if generator.Err != nil {
+			return 0
+		}
+		if x >= threshold {
+			return x % n
+		}
+	}
+}
+
+// [min,max]上均匀分布的整数,min>max时返回min
+func (generator *Generator) IntRange(min int, max int) int {
+	if min >= max {
+		return min
+	}
+	span := uint64(max) - uint64(min)
+	if span == math.MaxUint64 {
+		return min + int(generator.Uint64())
+	}
+	return min + int(generator.Uint64n(span+1))
+}
+
+// [0,max)上均匀分布的big.Int:取与max-1等长的随机比特,大于等于max时拒绝重取
+func (generator *Generator) Big_Int(max *big.Int) (*big.Int, error) {
+	if max.Sign() <= 0 {
+		return nil, errors.New("Big_Int error: max must be positive")
+	}
+	upper := new(big.Int).Sub(max, big.NewInt(1))
+	bit_length := upper.BitLen()
+	if bit_length == 0 {
+		return new(big.Int), nil
+	}
+	b := make([]byte, (bit_length+7)/8)
+	mask := byte(0xff >> (len(b)*8 - bit_length))
+	n := new(big.Int)
+	for {
+		if !generator.read(b) {
+			return nil, generator.Err
+		}
+		b[0] &= mask
+		n.SetBytes(b)
+		if n.Cmp(max) < 0 {
+			return n, nil
+		}
+	}
+}
+
+// [min,max]上均匀分布的big.Int,如SM2私钥取Big_Int_Range(1, n-2)
+func (generator *Generator) Big_Int_Range(min *big.Int, max *big.Int) (*big.Int, error) {
+	if min.Cmp(max) > 0 {
+		return nil, errors.New("Big_Int_Range error: min greater than max")
+	}
+	span := new(big.Int).Sub(max, min)
+	span.Add(span, big.NewInt(1))
+	n, err := generator.Big_Int(span)
+	if err != nil {
+		return nil, err
+	}
+	return n.Add(n, min), nil
+}
+
+// [0,1)上均匀分布的浮点数,精度为2^-53
+func (generator *Generator) Float64() float64 {
+	return float64(generator.Uint64()>>11) / (1 << 53)
+}
+
+// 以Fisher-Yates算法随机打乱n个元素,swap交换下标i与j处的元素
+func (generator *Generator) Shuffle(n int, swap func(i int, j int)) {
+	for i := n - 1; i > 0; i-- {
+		j := int(generator.Uint64n(uint64(i) + 1))
+		swap(i, j)
+	}
+}
+
+// 0至n-1的随机排列
+func (generator *Generator) Perm(n int) []int {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	generator.Shuffle(n, func(i int, j int) {
+		perm[i], perm[j] = perm[j], perm[i]
+	})
+	return perm
+}
+
+// 从items中有放回地均匀抽取k个元素,items为空时返回nil
+func Choice[T any](generator *Generator, items []T, k int) []T {
+	if len(items) == 0 {
+		return nil
+	}
+	chosen := make([]T, k)
+	for i := range chosen {
+		chosen[i] = items[generator.Uint64n(uint64(len(items)))]
+	}
+	return chosen
+}
@@ -0,0 +1,80 @@
+package random
+
+import (
+	"bufio"
+	"math"
+	"math/big"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	bias_samples    = 1 << 18 //每项检测的样本数
+	bias_alpha      = 0.001   //显著性水平
+	bias_categories = 64      //分桶数
+)
+
+// 等概率分组的卡方检验,返回P值
+func chi_square(observed []int, total int) float64 {
+	expected := float64(total) / float64(len(observed))
+	X := 0.0
+	for _, o := range observed {
+		X += (float64(o) - expected) * (float64(o) - expected) / expected
+	}
+	return tools.Igamc(float64(len(observed)-1)/2, X/2)
+}
+
+// 对sample的取值(0至categories-1)进行卡方检验
+func bias_test(categories int, sample func() int) float64 {
+	observed := make([]int, categories)
+	for i := 0; i < bias_samples; i++ {
+		observed[sample()]++
+	}
+	return chi_square(observed, bias_samples)
+}
+
+// 偏差自检:以固定种子的SM3 Hash_DRBG(带缓冲)为随机源,对各函数的输出进行卡方检验,P值均应不小于bias_alpha。
+// 其中Uint32n(3·2^30)按[0,2^30)、[2^30,2^31)、[2^31,3·2^30)分为三组,直接取模时前一组的概率为1/2,可检出取模偏差
+func Test_Bias() int {
+	seed := []byte("random bias self-test")
+	generator := New(bufio.NewReaderSize(drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil), 1<<16))
+	bound := new(big.Int).Lsh(big.NewInt(bias_categories*3), 200)
+	tests := []struct {
+		categories int
+		sample     func() int
+	}{
+		{7, func() int { return int(generator.Uint32n(7)) }},
+		{3, func() int { return int(generator.Uint32n(3<<30) >> 30) }},
+		{3, func() int { return int(generator.Uint64n(3<<62) >> 62) }},
+		{11, func() int { return generator.IntRange(-5, 5) + 5 }},
+		{bias_categories, func() int { return int(generator.Float64() * bias_categories) }},
+		{bias_categories * 3, func() int {
+			n, _ := generator.Big_Int(bound)
+			return int(n.Rsh(n, 200).Int64())
+		}},
+		//4个元素的全部24种排列
+		{24, func() int {
+			perm := generator.Perm(4)
+			index := 0
+			for i, v := range perm {
+				smaller := 0
+				for _, w := range perm[i+1:] {
+					if w < v {
+						smaller++
+					}
+				}
+				index = index*(4-i) + smaller
+			}
+			return index
+		}},
+		{10, func() int { return Choice(generator, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 1)[0] }},
+	}
+	for _, test := range tests {
+		p_value := bias_test(test.categories, test.sample)
+		if generator.Err != nil || math.IsNaN(p_value) || p_value < bias_alpha {
+			return -1
+		}
+	}
+	return 0
+}
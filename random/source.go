@@ -0,0 +1,53 @@
+package random
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+)
+
+const source_buffer_size = 4096 //缓冲区长度(单位:字节),每次调用SM3_DRBG_Generate输出32768比特
+
+// 以SM3 DRBG为随机源的math/rand/v2 Source,可直接用于rand.New(source)。
+// 每次从DRBG取source_buffer_size字节缓存,摊薄SM3_DRBG_Generate的调用开销;已取出的字节立即清零。
+// 可被多个协程并发使用。DRBG处于错误状态时panic
+type Source struct {
+	working_state *drbg.Working_State
+	mutex         sync.Mutex
+	buffer        [source_buffer_size]byte
+	offset        int
+}
+
+var _ rand.Source = (*Source)(nil)
+
+// 以已初始化的DRBG创建Source
+func New_Source(working_state *drbg.Working_State) *Source {
+	return &Source{working_state: working_state, offset: source_buffer_size}
+}
+
+// 初始化SM3 DRBG并创建Source
+func New_Source_SM3(Mode int, personalization_string string) *Source {
+	return New_Source(drbg.Init_DRBG_SM3(Mode, personalization_string))
+}
+
+// 均匀分布的64比特无符号整数
+func (source *Source) Uint64() uint64 {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if source.offset+8 > source_buffer_size {
+		random_bytes := source.working_state.SM3_DRBG_Generate(source_buffer_size*8, "")
+		if len(random_bytes) != source_buffer_size {
+			panic("random.Source: DRBG处于错误状态")
+		}
+		copy(source.buffer[:], random_bytes)
+		clear(random_bytes)
+		source.offset = 0
+	}
+	chunk := source.buffer[source.offset : source.offset+8]
+	x := binary.BigEndian.Uint64(chunk)
+	clear(chunk)
+	source.offset += 8
+	return x
+}
@@ -0,0 +1,129 @@
+package randtest
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const guard_bits = 64 //定点计算的保护位数
+
+// NIST参考输出(SP 800-22 Rev.1a附录B),依次为e、π、sqrt(2)的前10^6比特的P值
+// 线性复杂度检测的参考输出采用了π0=0.01047,与GM/T 0005-2021的分类概率不同,不参与比对
+var sp800_22_reference = []struct {
+	Name  string
+	Index int //Run_SP800_22结果中的序号
+	P     int //该项检测中P值的序号
+	Want  [3]float64
+}{
+	{"Frequency", 0, 0, [3]float64{0.953749, 0.578211, 0.811881}},
+	{"Block Frequency", 1, 0, [3]float64{0.211072, 0.380615, 0.833222}},
+	{"Cumulative Sums(forward)", 2, 0, [3]float64{0.669887, 0.628308, 0.879009}},
+	{"Cumulative Sums(reverse)", 2, 1, [3]float64{0.724266, 0.663369, 0.957206}},
+	{"Runs", 3, 0, [3]float64{0.561917, 0.419268, 0.313427}},
+	{"Longest Run", 4, 0, [3]float64{0.718945, 0.024390, 0.012117}},
+	{"Rank", 5, 0, [3]float64{0.306156, 0.083553, 0.823810}},
+	{"FFT", 6, 0, [3]float64{0.847187, 0.010186, 0.581909}},
+	{"Non-Overlapping Template(000000001)", 7, 0, [3]float64{0.078790, 0.165757, 0.569461}},
+	{"Overlapping Template", 8, 0, [3]float64{0.110434, 0.296897, 0.791982}},
+	{"Universal", 9, 0, [3]float64{0.282568, 0.669012, 0.130805}},
+	{"Approximate Entropy", 10, 0, [3]float64{0.700073, 0.361595, 0.884740}},
+	{"Random Excursions(x=+1)", 11, 4, [3]float64{0.786868, 0.844143, 0.216235}},
+	{"Random Excursions Variant(x=-1)", 12, 8, [3]float64{0.826009, 0.760966, 0.566118}},
+	{"Serial(P1)", 14, 0, [3]float64{0.766182, 0.143005, 0.861925}},
+}
+
+// 定点数->比特流,取value的二进制表示的前n比特
+func fixed2stream(value *big.Int, n int) Bit_Stream {
+	return Bits2Stream(value.Text(2)[:n])
+}
+
+// 1/k!求和的二分拆分,返回P/Q=sum_{k=a+1}^{b} a!/k!
+func e_split(a int64, b int64) (*big.Int, *big.Int) {
+	if b-a == 1 {
+		return big.NewInt(1), big.NewInt(b)
+	}
+	m := (a + b) / 2
+	P1, Q1 := e_split(a, m)
+	P2, Q2 := e_split(m, b)
+	P := new(big.Int).Mul(P1, Q2)
+	P.Add(P, P2)
+	return P, Q1.Mul(Q1, Q2)
+}
+
+// 自然常数e的二进制展开的前n比特(含整数部分)
+func E_Bits(n int) Bit_Stream {
+	//取项数N使N!>2^(n+guard_bits)
+	N := int64(1)
+	for log2_factorial := 0.0; log2_factorial < float64(n+guard_bits); N++ {
+		log2_factorial += math.Log2(float64(N + 1))
+	}
+	P, Q := e_split(0, N)
+	P.Add(P, Q)
+	P.Lsh(P, uint(n-2+guard_bits))
+	P.Quo(P, Q)
+	return fixed2stream(P.Rsh(P, guard_bits), n)
+}
+
+// Chudnovsky级数的二分拆分
+func pi_split(a int64, b int64) (*big.Int, *big.Int, *big.Int) {
+	if b-a == 1 {
+		if a == 0 {
+			return big.NewInt(1), big.NewInt(1), big.NewInt(13591409)
+		}
+		P := big.NewInt((6*a - 5) * (2*a - 1) * (6*a - 1))
+		Q := big.NewInt(a)
+		Q.Mul(Q, Q).Mul(Q, big.NewInt(a)).Mul(Q, big.NewInt(10939058860032000))
+		T := new(big.Int).Mul(P, big.NewInt(13591409+545140134*a))
+		if a%2 == 1 {
+			T.Neg(T)
+		}
+		return P, Q, T
+	}
+	m := (a + b) / 2
+	P1, Q1, T1 := pi_split(a, m)
+	P2, Q2, T2 := pi_split(m, b)
+	T := new(big.Int).Mul(T1, Q2)
+	T.Add(T, new(big.Int).Mul(P1, T2))
+	return P1.Mul(P1, P2), Q1.Mul(Q1, Q2), T
+}
+
+// 圆周率π的二进制展开的前n比特(含整数部分)
+func Pi_Bits(n int) Bit_Stream {
+	//Chudnovsky级数每项约增加47.11比特精度
+	_, Q, T := pi_split(0, int64(float64(n+guard_bits)/47.11)+2)
+	shift := uint(n - 2 + guard_bits)
+	//pi*2^shift = 426880*sqrt(10005*2^(2*shift))*Q/T
+	root := new(big.Int).Lsh(big.NewInt(10005), 2*shift)
+	root.Sqrt(root)
+	numerator := root.Mul(root, Q).Mul(root, big.NewInt(426880))
+	numerator.Quo(numerator, T)
+	return fixed2stream(numerator.Rsh(numerator, guard_bits), n)
+}
+
+// sqrt(2)的二进制展开的前n比特(含整数部分)
+func Sqrt2_Bits(n int) Bit_Stream {
+	value := new(big.Int).Lsh(big.NewInt(2), uint(2*(n-1)))
+	return fixed2stream(value.Sqrt(value), n)
+}
+
+// SP 800-22检测自检:对e、π、sqrt(2)的前10^6比特运行检测,与NIST参考输出比对
+func Test_SP800_22() int {
+	n := 1000000
+	streams := []Bit_Stream{E_Bits(n), Pi_Bits(n), Sqrt2_Bits(n)}
+	names := []string{"e", "pi", "sqrt2"}
+	flag := 0
+	for i, stream := range streams {
+		results := Run_SP800_22(stream)
+		for _, reference := range sp800_22_reference {
+			want := reference.Want[i]
+			//参考输出保留6位小数,允许末位舍入误差
+			P_Value := results[reference.Index].P_Value
+			if reference.P >= len(P_Value) || math.Abs(P_Value[reference.P]-want) > 1.5e-6 {
+				fmt.Println("SP 800-22自检未通过:", names[i], reference.Name)
+				flag = -1
+			}
+		}
+	}
+	return flag
+}
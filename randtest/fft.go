@@ -0,0 +1,85 @@
+package randtest
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// 基2快速傅立叶变换(原地),长度必须为2的幂,inverse表示逆变换(不含1/n缩放)
+func fft_radix2(x []complex128, inverse bool) {
+	n := len(x)
+	shift := 64 - bits.Len(uint(n-1))
+	for i := 0; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		twiddles := make([]complex128, half)
+		twiddles[0] = 1
+		for k := 1; k < half; k++ {
+			if k%64 == 0 {
+				twiddles[k] = cmplx.Rect(1, sign*2*math.Pi*float64(k)/float64(size))
+			} else {
+				twiddles[k] = twiddles[k-1] * step
+			}
+		}
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				t := twiddles[k] * x[start+k+half]
+				x[start+k+half] = x[start+k] - t
+				x[start+k] += t
+			}
+		}
+	}
+}
+
+// 任意长度的离散傅立叶变换,长度非2的幂时使用Bluestein算法
+func DFT(x []complex128) []complex128 {
+	n := len(x)
+	if n == 0 {
+		return nil
+	}
+	if n&(n-1) == 0 {
+		y := make([]complex128, n)
+		copy(y, x)
+		fft_radix2(y, false)
+		return y
+	}
+	m := 1 << bits.Len(uint(2*n-1))
+	chirp := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		//k^2 mod 2n以整数计算,避免大k时的精度损失
+		k2 := (uint64(k) * uint64(k)) % uint64(2*n)
+		chirp[k] = cmplx.Rect(1, -math.Pi*float64(k2)/float64(n))
+	}
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * chirp[k]
+	}
+	b[0] = cmplx.Conj(chirp[0])
+	for k := 1; k < n; k++ {
+		b[k] = cmplx.Conj(chirp[k])
+		b[m-k] = b[k]
+	}
+	fft_radix2(a, false)
+	fft_radix2(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	fft_radix2(a, true)
+	y := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		y[k] = a[k] * chirp[k] / complex(float64(m), 0)
+	}
+	return y
+}
@@ -0,0 +1,500 @@
+package randtest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+// 块内最大游程检测的分类表
+type longest_run_table struct {
+	M  int       //块长度
+	V  []int     //各分类的游程长度上界(首项为下界)
+	Pi []float64 //各分类的理论概率
+}
+
+var longest_run_tables = []longest_run_table{
+	{8, []int{1, 2, 3, 4}, []float64{0.2148, 0.3672, 0.2305, 0.1875}},
+	{128, []int{4, 5, 6, 7, 8, 9}, []float64{0.1174, 0.2430, 0.2493, 0.1752, 0.1027, 0.1124}},
+	{10000, []int{10, 11, 12, 13, 14, 15, 16}, []float64{0.0882, 0.2092, 0.2483, 0.1933, 0.1208, 0.0675, 0.0727}},
+}
+
+var linear_complexity_pi = []float64{0.010417, 0.03125, 0.125, 0.5, 0.25, 0.0625, 0.020833} //线性复杂度检测各分类的理论概率
+
+var universal_expected = []float64{0, 0.7326495, 1.5374383, 2.4016068, 3.3112247, 4.2534266, 5.2177052, 6.1962507, 7.1836656, 8.1764248, 9.1723243, 10.170032, 11.168765, 12.168070, 13.167693, 14.167488, 15.167379} //Maurer通用统计检测的期望值
+var universal_variance = []float64{0, 0.690, 1.338, 1.901, 2.358, 2.705, 2.954, 3.125, 3.238, 3.311, 3.356, 3.384, 3.401, 3.410, 3.416, 3.419, 3.421}                                                                 //Maurer通用统计检测的方差
+
+// 标准正态分布函数
+func normal_cdf(x float64) float64 {
+	return math.Erfc(-x/math.Sqrt2) / 2
+}
+
+// 由标准正态统计量V计算P值与Q值
+func normal_result(name string, V float64) Result {
+	return single_result(name, math.Erfc(math.Abs(V)/math.Sqrt2), math.Erfc(V/math.Sqrt2)/2)
+}
+
+// 卡方统计量
+func chi_square(observed []float64, expected []float64) float64 {
+	V := 0.0
+	for i := range observed {
+		V += (observed[i] - expected[i]) * (observed[i] - expected[i]) / expected[i]
+	}
+	return V
+}
+
+// 单比特频数检测
+func Monobit_Frequency(stream Bit_Stream) Result {
+	n := len(stream)
+	S := 0
+	for _, b := range stream {
+		S += 2*int(b) - 1
+	}
+	return normal_result("单比特频数检测", float64(S)/math.Sqrt(float64(n)))
+}
+
+// 块内频数检测
+func Block_Frequency(stream Bit_Stream, m int) Result {
+	N := len(stream) / m
+	V := 0.0
+	for i := 0; i < N; i++ {
+		ones := 0
+		for _, b := range stream[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+		pi := float64(ones)/float64(m) - 0.5
+		V += pi * pi
+	}
+	V *= 4 * float64(m)
+	P := tools.Igamc(float64(N)/2, V/2)
+	return single_result(fmt.Sprintf("块内频数检测(m=%d)", m), P, P)
+}
+
+// 扑克检测
+func Poker(stream Bit_Stream, m int) Result {
+	N := len(stream) / m
+	counts := make([]float64, 1<<m)
+	for i := 0; i < N; i++ {
+		value := 0
+		for _, b := range stream[i*m : (i+1)*m] {
+			value = value<<1 | int(b)
+		}
+		counts[value]++
+	}
+	V := 0.0
+	for _, c := range counts {
+		V += c * c
+	}
+	V = V*float64(int(1)<<m)/float64(N) - float64(N)
+	P := tools.Igamc(float64(int(1)<<m-1)/2, V/2)
+	return single_result(fmt.Sprintf("扑克检测(m=%d)", m), P, P)
+}
+
+// 长度为m的重叠子序列(循环)的psi^2统计量
+func psi_square(stream Bit_Stream, m int) float64 {
+	if m <= 0 {
+		return 0
+	}
+	n := len(stream)
+	counts := make([]float64, 1<<m)
+	mask := 1<<m - 1
+	value := 0
+	for i := 0; i < m-1; i++ {
+		value = value<<1 | int(stream[i])
+	}
+	for i := 0; i < n; i++ {
+		value = (value<<1 | int(stream[(i+m-1)%n])) & mask
+		counts[value]++
+	}
+	sum := 0.0
+	for _, c := range counts {
+		sum += c * c
+	}
+	return sum*float64(int(1)<<m)/float64(n) - float64(n)
+}
+
+// 重叠子序列检测
+func Serial(stream Bit_Stream, m int) Result {
+	psi_m := psi_square(stream, m)
+	psi_m1 := psi_square(stream, m-1)
+	psi_m2 := psi_square(stream, m-2)
+	P1 := tools.Igamc(math.Pow(2, float64(m-2)), (psi_m-psi_m1)/2)
+	P2 := tools.Igamc(math.Pow(2, float64(m-3)), (psi_m-2*psi_m1+psi_m2)/2)
+	return Result{Name: fmt.Sprintf("重叠子序列检测(m=%d)", m), P_Value: []float64{P1, P2}, Q_Value: []float64{P1, P2}}
+}
+
+// 游程总数检测
+func Runs(stream Bit_Stream) Result {
+	n := float64(len(stream))
+	ones := 0
+	V := 1
+	for i, b := range stream {
+		ones += int(b)
+		if i > 0 && b != stream[i-1] {
+			V++
+		}
+	}
+	pi := float64(ones) / n
+	Z := (float64(V) - 2*n*pi*(1-pi)) / (2 * math.Sqrt(2*n) * pi * (1 - pi))
+	return single_result("游程总数检测", math.Erfc(math.Abs(Z)), math.Erfc(Z)/2)
+}
+
+// 游程分布检测
+func Runs_Distribution(stream Bit_Stream) Result {
+	n := len(stream)
+	k := 1
+	for float64(n-k+2)/math.Pow(2, float64(k+3)) >= 5 {
+		k++
+	}
+	e := make([]float64, k)
+	for i := 1; i <= k; i++ {
+		e[i-1] = float64(n-i+3) / math.Pow(2, float64(i+2))
+	}
+	b := make([]float64, k)
+	g := make([]float64, k)
+	length := 1
+	for i := 1; i <= n; i++ {
+		if i < n && stream[i] == stream[i-1] {
+			length++
+			continue
+		}
+		if length <= k {
+			if stream[i-1] == 1 {
+				b[length-1]++
+			} else {
+				g[length-1]++
+			}
+		}
+		length = 1
+	}
+	V := chi_square(b, e) + chi_square(g, e)
+	P := tools.Igamc(float64(k-1), V/2)
+	return single_result("游程分布检测", P, P)
+}
+
+// 块内最大游程检测
+func Longest_Run(stream Bit_Stream, m int) Result {
+	var table *longest_run_table
+	for i := range longest_run_tables {
+		if longest_run_tables[i].M == m {
+			table = &longest_run_tables[i]
+		}
+	}
+	name := fmt.Sprintf("块内最大游程检测(m=%d)", m)
+	if table == nil {
+		fmt.Println("Longest_Run error!")
+		return single_result(name, math.NaN(), math.NaN())
+	}
+	N := len(stream) / m
+	K := len(table.V) - 1
+	observed := make([]float64, K+1)
+	for i := 0; i < N; i++ {
+		run := 0
+		longest := 0
+		for _, b := range stream[i*m : (i+1)*m] {
+			if b == 1 {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+		j := 0
+		for j < K && longest > table.V[j] {
+			j++
+		}
+		observed[j]++
+	}
+	expected := make([]float64, K+1)
+	for j, pi := range table.Pi {
+		expected[j] = float64(N) * pi
+	}
+	P := tools.Igamc(float64(K)/2, chi_square(observed, expected)/2)
+	return single_result(name, P, P)
+}
+
+// 二元推导检测
+func Binary_Derivation(stream Bit_Stream, k int) Result {
+	n := len(stream)
+	derived := make([]byte, n)
+	copy(derived, stream)
+	for j := 1; j <= k; j++ {
+		for i := 0; i < n-j; i++ {
+			derived[i] ^= derived[i+1]
+		}
+	}
+	S := 0
+	for _, b := range derived[:n-k] {
+		S += 2*int(b) - 1
+	}
+	return normal_result(fmt.Sprintf("二元推导检测(k=%d)", k), float64(S)/math.Sqrt(float64(n-k)))
+}
+
+// 自相关检测
+func Autocorrelation(stream Bit_Stream, d int) Result {
+	n := len(stream)
+	A := 0
+	for i := 0; i < n-d; i++ {
+		A += int(stream[i] ^ stream[i+d])
+	}
+	V := 2 * (float64(A) - float64(n-d)/2) / math.Sqrt(float64(n-d))
+	return normal_result(fmt.Sprintf("自相关检测(d=%d)", d), V)
+}
+
+// GF(2)上矩阵的秩,每行以uint64切片表示
+func Binary_Rank(rows [][]uint64, columns int) int {
+	rank := 0
+	for c := 0; c < columns && rank < len(rows); c++ {
+		word := c / 64
+		bit := uint64(1) << (c % 64)
+		pivot := -1
+		for r := rank; r < len(rows); r++ {
+			if rows[r][word]&bit != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot == -1 {
+			continue
+		}
+		rows[rank], rows[pivot] = rows[pivot], rows[rank]
+		for r := 0; r < len(rows); r++ {
+			if r != rank && rows[r][word]&bit != 0 {
+				for w := range rows[r] {
+					rows[r][w] ^= rows[rank][w]
+				}
+			}
+		}
+		rank++
+	}
+	return rank
+}
+
+// M×Q随机二元矩阵的秩为r的概率
+func Rank_Probability(r int, M int, Q int) float64 {
+	log_p := float64(r*(Q+M-r)-M*Q) * math.Ln2
+	for i := 0; i < r; i++ {
+		log_p += math.Log((1-math.Pow(2, float64(i-Q)))*(1-math.Pow(2, float64(i-M)))) - math.Log(1-math.Pow(2, float64(i-r)))
+	}
+	return math.Exp(log_p)
+}
+
+// 矩阵秩检测
+func Matrix_Rank(stream Bit_Stream, M int, Q int) Result {
+	N := len(stream) / (M * Q)
+	words := (Q + 63) / 64
+	observed := make([]float64, 3)
+	for k := 0; k < N; k++ {
+		rows := make([][]uint64, M)
+		for r := 0; r < M; r++ {
+			rows[r] = make([]uint64, words)
+			for c := 0; c < Q; c++ {
+				if stream[k*M*Q+r*Q+c] == 1 {
+					rows[r][c/64] |= 1 << (c % 64)
+				}
+			}
+		}
+		rank := Binary_Rank(rows, Q)
+		switch {
+		case rank == min(M, Q):
+			observed[0]++
+		case rank == min(M, Q)-1:
+			observed[1]++
+		default:
+			observed[2]++
+		}
+	}
+	p_full := Rank_Probability(min(M, Q), M, Q)
+	p_minus := Rank_Probability(min(M, Q)-1, M, Q)
+	expected := []float64{float64(N) * p_full, float64(N) * p_minus, float64(N) * (1 - p_full - p_minus)}
+	P := tools.Igamc(1, chi_square(observed, expected)/2)
+	return single_result(fmt.Sprintf("矩阵秩检测(M=%d,Q=%d)", M, Q), P, P)
+}
+
+// 由累加和的最大偏移z计算P值
+func cumulative_sums_p(n int, z int) float64 {
+	nf := float64(n)
+	zf := float64(z)
+	sqrt_n := math.Sqrt(nf)
+	sum1 := 0.0
+	for k := int(math.Floor((-nf/zf + 1) / 4)); k <= int(math.Floor((nf/zf-1)/4)); k++ {
+		sum1 += normal_cdf(float64(4*k+1)*zf/sqrt_n) - normal_cdf(float64(4*k-1)*zf/sqrt_n)
+	}
+	sum2 := 0.0
+	for k := int(math.Floor((-nf/zf - 3) / 4)); k <= int(math.Floor((nf/zf-1)/4)); k++ {
+		sum2 += normal_cdf(float64(4*k+3)*zf/sqrt_n) - normal_cdf(float64(4*k+1)*zf/sqrt_n)
+	}
+	return 1 - sum1 + sum2
+}
+
+// 累加和检测(前向与后向)
+func Cumulative_Sums(stream Bit_Stream) Result {
+	n := len(stream)
+	S := 0
+	forward := 0
+	for _, b := range stream {
+		S += 2*int(b) - 1
+		forward = max(forward, S, -S)
+	}
+	S = 0
+	backward := 0
+	for i := n - 1; i >= 0; i-- {
+		S += 2*int(stream[i]) - 1
+		backward = max(backward, S, -S)
+	}
+	P1 := cumulative_sums_p(n, forward)
+	P2 := cumulative_sums_p(n, backward)
+	return Result{Name: "累加和检测", P_Value: []float64{P1, P2}, Q_Value: []float64{P1, P2}}
+}
+
+// 长度为m的重叠子序列(循环)的phi统计量
+func phi(stream Bit_Stream, m int) float64 {
+	if m == 0 {
+		return 0
+	}
+	n := len(stream)
+	counts := make([]float64, 1<<m)
+	mask := 1<<m - 1
+	value := 0
+	for i := 0; i < m-1; i++ {
+		value = value<<1 | int(stream[i])
+	}
+	for i := 0; i < n; i++ {
+		value = (value<<1 | int(stream[(i+m-1)%n])) & mask
+		counts[value]++
+	}
+	sum := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			pi := c / float64(n)
+			sum += pi * math.Log(pi)
+		}
+	}
+	return sum
+}
+
+// 近似熵检测
+func Approximate_Entropy(stream Bit_Stream, m int) Result {
+	n := float64(len(stream))
+	ApEn := phi(stream, m) - phi(stream, m+1)
+	V := 2 * n * (math.Ln2 - ApEn)
+	P := tools.Igamc(math.Pow(2, float64(m-1)), V/2)
+	return single_result(fmt.Sprintf("近似熵检测(m=%d)", m), P, P)
+}
+
+// Berlekamp-Massey算法求二元序列的线性复杂度
+func Berlekamp_Massey(sequence []byte) int {
+	n := len(sequence)
+	C := make([]byte, n+1)
+	B := make([]byte, n+1)
+	T := make([]byte, n+1)
+	C[0] = 1
+	B[0] = 1
+	L := 0
+	m := -1
+	for N := 0; N < n; N++ {
+		d := sequence[N]
+		for i := 1; i <= L; i++ {
+			d ^= C[i] & sequence[N-i]
+		}
+		if d == 0 {
+			continue
+		}
+		copy(T, C)
+		for i := 0; i+N-m <= n; i++ {
+			C[i+N-m] ^= B[i]
+		}
+		if 2*L <= N {
+			L = N + 1 - L
+			m = N
+			copy(B, T)
+		}
+	}
+	return L
+}
+
+// 线性复杂度检测
+func Linear_Complexity(stream Bit_Stream, M int) Result {
+	N := len(stream) / M
+	Mf := float64(M)
+	sign := 1.0
+	if M%2 == 1 {
+		sign = -1
+	}
+	mu := Mf/2 + (9-sign)/36 - (Mf/3+2.0/9)/math.Pow(2, Mf)
+	observed := make([]float64, len(linear_complexity_pi))
+	for i := 0; i < N; i++ {
+		L := Berlekamp_Massey(stream[i*M : (i+1)*M])
+		T := sign*(float64(L)-mu) + 2.0/9
+		switch {
+		case T <= -2.5:
+			observed[0]++
+		case T <= -1.5:
+			observed[1]++
+		case T <= -0.5:
+			observed[2]++
+		case T <= 0.5:
+			observed[3]++
+		case T <= 1.5:
+			observed[4]++
+		case T <= 2.5:
+			observed[5]++
+		default:
+			observed[6]++
+		}
+	}
+	expected := make([]float64, len(linear_complexity_pi))
+	for i, pi := range linear_complexity_pi {
+		expected[i] = float64(N) * pi
+	}
+	P := tools.Igamc(3, chi_square(observed, expected)/2)
+	return single_result(fmt.Sprintf("线性复杂度检测(m=%d)", M), P, P)
+}
+
+// Maurer通用统计检测
+func Universal(stream Bit_Stream, L int, Q int) Result {
+	name := fmt.Sprintf("Maurer通用统计检测(L=%d,Q=%d)", L, Q)
+	K := len(stream)/L - Q
+	if L < 1 || L >= len(universal_expected) || K <= 0 {
+		fmt.Println("Universal error!")
+		return single_result(name, math.NaN(), math.NaN())
+	}
+	T := make([]int, 1<<L)
+	sum := 0.0
+	for i := 1; i <= Q+K; i++ {
+		value := 0
+		for _, b := range stream[(i-1)*L : i*L] {
+			value = value<<1 | int(b)
+		}
+		if i > Q {
+			sum += math.Log2(float64(i - T[value]))
+		}
+		T[value] = i
+	}
+	f_n := sum / float64(K)
+	Lf := float64(L)
+	c := 0.7 - 0.8/Lf + (4+32/Lf)*math.Pow(float64(K), -3/Lf)/15
+	sigma := c * math.Sqrt(universal_variance[L]/float64(K))
+	return normal_result(name, (f_n-universal_expected[L])/sigma)
+}
+
+// 离散傅立叶检测,divisor为方差公式n*0.95*0.05/divisor中的除数
+func Discrete_Fourier_Transform(stream Bit_Stream, divisor float64) Result {
+	n := len(stream)
+	X := make([]complex128, n)
+	for i, b := range stream {
+		X[i] = complex(float64(2*int(b)-1), 0)
+	}
+	F := DFT(X)
+	T := math.Sqrt(2.995732274 * float64(n))
+	N0 := 0.95 * float64(n) / 2
+	N1 := 0
+	for j := 0; j < n/2; j++ {
+		if math.Hypot(real(F[j]), imag(F[j])) < T {
+			N1++
+		}
+	}
+	d := (float64(N1) - N0) / math.Sqrt(0.95*0.05*float64(n)/divisor)
+	return normal_result("离散傅立叶检测", d)
+}
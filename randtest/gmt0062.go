@@ -0,0 +1,183 @@
+package randtest
+
+import (
+	"io"
+	"sync"
+)
+
+// 检测项
+type Detection_Test func(stream Bit_Stream) Result
+
+// 检测方案:采集Samples组Sample_Bits比特的样本,每个检测项至少Min_Passed组通过
+type Detection_Scheme struct {
+	Samples     int              //样本组数
+	Sample_Bits int              //每组样本的比特数
+	Min_Passed  int              //每个检测项至少通过的组数
+	Tests       []Detection_Test //检测项
+	Alpha       float64          //显著性水平,为0时使用Alpha
+}
+
+// 单次检测的显著性水平。单次检测针对每个关键输出进行,且未通过时会导致重播种或进入错误状态,
+// 若取Alpha=0.01,健康的DRBG约每100次输出即误报一次;取10^-6时只检出全0、全1等明显失效的输出
+const Single_Alpha = 1e-6
+
+// 检测方案使用的显著性水平
+func (scheme Detection_Scheme) alpha() float64 {
+	if scheme.Alpha == 0 {
+		return Alpha
+	}
+	return scheme.Alpha
+}
+
+// 检测级别(参照GM/T 0062-2018的上电检测、周期检测与单次检测)
+type Detection_Level struct {
+	Name              string           //级别名称
+	Power_On          Detection_Scheme //上电检测
+	Periodic          Detection_Scheme //周期检测
+	Periodic_Interval int              //周期检测的间隔(单位:输出次数)
+	Single            Detection_Scheme //单次检测
+}
+
+// 单次检测:对关键输出(如密钥)进行扑克检测(m=2),显著性水平为Single_Alpha
+var single_scheme = Detection_Scheme{
+	Samples:    1,
+	Min_Passed: 1,
+	Alpha:      Single_Alpha,
+	Tests: []Detection_Test{
+		func(stream Bit_Stream) Result { return Poker(stream, 2) },
+	},
+}
+
+// 10^4比特样本适用的检测项
+var small_sample_tests = []Detection_Test{
+	Monobit_Frequency,
+	func(stream Bit_Stream) Result { return Block_Frequency(stream, 1000) },
+	func(stream Bit_Stream) Result { return Poker(stream, 4) },
+	func(stream Bit_Stream) Result { return Serial(stream, 3) },
+	Runs,
+	Runs_Distribution,
+	func(stream Bit_Stream) Result { return Longest_Run(stream, 128) },
+	func(stream Bit_Stream) Result { return Binary_Derivation(stream, 3) },
+	func(stream Bit_Stream) Result { return Autocorrelation(stream, 1) },
+	Cumulative_Sums,
+	func(stream Bit_Stream) Result { return Approximate_Entropy(stream, 2) },
+}
+
+// 10^5比特样本适用的检测项
+var medium_sample_tests = append(small_sample_tests[:len(small_sample_tests):len(small_sample_tests)],
+	func(stream Bit_Stream) Result { return Autocorrelation(stream, 8) },
+	func(stream Bit_Stream) Result { return Matrix_Rank(stream, 32, 32) },
+	func(stream Bit_Stream) Result { return Approximate_Entropy(stream, 5) },
+	func(stream Bit_Stream) Result { return Linear_Complexity(stream, 500) },
+	func(stream Bit_Stream) Result {
+		return Discrete_Fourier_Transform(stream, Default_Parameters().DFT_Divisor)
+	},
+)
+
+// 按检测参数生成GM/T 0005-2021的全部检测项,与Run_All一致
+func parameter_tests(parameters Parameters) []Detection_Test {
+	tests := []Detection_Test{
+		Monobit_Frequency,
+		func(stream Bit_Stream) Result { return Block_Frequency(stream, parameters.Block_Frequency_M) },
+	}
+	for _, m := range parameters.Poker_M {
+		tests = append(tests, func(stream Bit_Stream) Result { return Poker(stream, m) })
+	}
+	for _, m := range parameters.Serial_M {
+		tests = append(tests, func(stream Bit_Stream) Result { return Serial(stream, m) })
+	}
+	tests = append(tests,
+		Runs,
+		Runs_Distribution,
+		func(stream Bit_Stream) Result { return Longest_Run(stream, parameters.Longest_Run_M) })
+	for _, k := range parameters.Binary_Derivation_K {
+		tests = append(tests, func(stream Bit_Stream) Result { return Binary_Derivation(stream, k) })
+	}
+	for _, d := range parameters.Autocorrelation_D {
+		tests = append(tests, func(stream Bit_Stream) Result { return Autocorrelation(stream, d) })
+	}
+	tests = append(tests,
+		func(stream Bit_Stream) Result {
+			return Matrix_Rank(stream, parameters.Matrix_Rank_M, parameters.Matrix_Rank_Q)
+		},
+		Cumulative_Sums)
+	for _, m := range parameters.Approximate_Entropy_M {
+		tests = append(tests, func(stream Bit_Stream) Result { return Approximate_Entropy(stream, m) })
+	}
+	for _, m := range parameters.Linear_Complexity_M {
+		tests = append(tests, func(stream Bit_Stream) Result { return Linear_Complexity(stream, m) })
+	}
+	tests = append(tests,
+		func(stream Bit_Stream) Result {
+			return Universal(stream, parameters.Universal_L, parameters.Universal_Q)
+		},
+		func(stream Bit_Stream) Result { return Discrete_Fourier_Transform(stream, parameters.DFT_Divisor) })
+	return tests
+}
+
+// 检测级别1:适用于资源受限的产品
+func Detection_Level_1() Detection_Level {
+	scheme := Detection_Scheme{Samples: 20, Sample_Bits: 10000, Min_Passed: 18, Tests: small_sample_tests}
+	single := single_scheme
+	single.Sample_Bits = 256
+	return Detection_Level{Name: "1", Power_On: scheme, Periodic: scheme, Periodic_Interval: 1 << 16, Single: single}
+}
+
+// 检测级别2:适用于一般产品
+func Detection_Level_2() Detection_Level {
+	scheme := Detection_Scheme{Samples: 20, Sample_Bits: 100000, Min_Passed: 18, Tests: medium_sample_tests}
+	single := single_scheme
+	single.Sample_Bits = 256
+	return Detection_Level{Name: "2", Power_On: scheme, Periodic: scheme, Periodic_Interval: 1 << 20, Single: single}
+}
+
+// 检测级别3:适用于服务器等高性能产品
+func Detection_Level_3() Detection_Level {
+	power_on := Detection_Scheme{Samples: 20, Sample_Bits: 1000000, Min_Passed: 18, Tests: parameter_tests(Default_Parameters())}
+	periodic := Detection_Scheme{Samples: 20, Sample_Bits: 100000, Min_Passed: 18, Tests: medium_sample_tests}
+	single := single_scheme
+	single.Sample_Bits = 256
+	return Detection_Level{Name: "3", Power_On: power_on, Periodic: periodic, Periodic_Interval: 1 << 20, Single: single}
+}
+
+// 按检测方案对一组样本进行检测,各样本并行检测
+func (scheme Detection_Scheme) Detect_Stream(streams []Bit_Stream) bool {
+	passed := make([][]bool, len(streams))
+	var wait_group sync.WaitGroup
+	for i, stream := range streams {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			passed[i] = make([]bool, len(scheme.Tests))
+			for j, test := range scheme.Tests {
+				passed[i][j] = test(stream).Pass(scheme.alpha())
+			}
+		}()
+	}
+	wait_group.Wait()
+	for j := range scheme.Tests {
+		count := 0
+		for i := range streams {
+			if passed[i][j] {
+				count++
+			}
+		}
+		if count < scheme.Min_Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// 从reader读取样本并按检测方案进行检测
+func (scheme Detection_Scheme) Detect(reader io.Reader) bool {
+	streams := make([]Bit_Stream, scheme.Samples)
+	for i := range streams {
+		data := make([]byte, (scheme.Sample_Bits+7)/8)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return false
+		}
+		streams[i] = New_Bit_Stream(data)[:scheme.Sample_Bits]
+	}
+	return scheme.Detect_Stream(streams)
+}
@@ -0,0 +1,177 @@
+package randtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	judge_samples     = 1000    //GM/T 0005-2021规定的样本个数
+	judge_sample_bits = 1000000 //GM/T 0005-2021规定的样本长度(单位:比特)
+)
+
+// 样本集判定配置
+type Judge_Config struct {
+	Samples     int        //样本个数
+	Sample_Bits int        //样本长度(单位:比特,须为8的倍数)
+	Workers     int        //并行的goroutine数量
+	Parameters  Parameters //检测参数
+}
+
+// 单项检测的样本集判定结果
+type Test_Report struct {
+	Name        string            //检测名称
+	Passed      int               //通过的样本个数
+	Total       int               //样本个数
+	Proportion  float64           //通过率
+	Lower_Bound float64           //通过率的下界
+	P_Value_T   float64           //Q值分布均匀性的P值
+	Histogram   [q_value_bins]int //P值的分布直方图
+	Pass        bool              //是否通过
+}
+
+// 样本集判定报告
+type Judge_Report struct {
+	Samples int           //样本个数
+	Tests   []Test_Report //各项检测的判定结果
+	Pass    bool          //是否全部通过
+}
+
+// 默认样本集判定配置
+func Default_Judge_Config() Judge_Config {
+	return Judge_Config{
+		Samples:     judge_samples,
+		Sample_Bits: judge_sample_bits,
+		Workers:     runtime.NumCPU(),
+		Parameters:  Default_Parameters(),
+	}
+}
+
+// 通过率的下界:(1-α)-3*sqrt(α(1-α)/s)
+func Proportion_Lower_Bound(s int, alpha float64) float64 {
+	return 1 - alpha - 3*math.Sqrt(alpha*(1-alpha)/float64(s))
+}
+
+// 展开为单P值的检测项,多P值的检测(重叠子序列、累加和)每个P值单独判定
+func expand_results(results []Result) []Result {
+	expanded := make([]Result, 0, len(results))
+	for _, result := range results {
+		if len(result.P_Value) == 1 {
+			expanded = append(expanded, result)
+			continue
+		}
+		for i := range result.P_Value {
+			expanded = append(expanded, single_result(fmt.Sprintf("%s(P%d)", result.Name, i+1), result.P_Value[i], result.Q_Value[i]))
+		}
+	}
+	return expanded
+}
+
+// 从reader读取样本并判定,reader可为样本文件或DRBG
+func Judge(reader io.Reader, config Judge_Config) (Judge_Report, error) {
+	if config.Sample_Bits <= 0 || config.Sample_Bits%8 != 0 || config.Samples <= 0 {
+		return Judge_Report{}, errors.New("Judge error: invalid config")
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	type job struct {
+		Index int
+		Data  []byte
+	}
+	jobs := make(chan job, config.Workers)
+	sample_results := make([][]Result, config.Samples)
+	var wait_group sync.WaitGroup
+	for w := 0; w < config.Workers; w++ {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			for j := range jobs {
+				sample_results[j.Index] = expand_results(Run_All(New_Bit_Stream(j.Data), config.Parameters))
+			}
+		}()
+	}
+	s := 0
+	var read_err error
+	for s < config.Samples {
+		data := make([]byte, config.Sample_Bits/8)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				read_err = err
+			}
+			break
+		}
+		jobs <- job{s, data}
+		s++
+	}
+	close(jobs)
+	wait_group.Wait()
+	if read_err != nil {
+		return Judge_Report{}, read_err
+	}
+	if s == 0 {
+		return Judge_Report{}, errors.New("Judge error: no complete sample")
+	}
+	return judge_results(sample_results[:s]), nil
+}
+
+// 由各样本的检测结果生成判定报告
+func judge_results(sample_results [][]Result) Judge_Report {
+	s := len(sample_results)
+	report := Judge_Report{Samples: s, Pass: true}
+	lower_bound := Proportion_Lower_Bound(s, Alpha)
+	for i, first := range sample_results[0] {
+		test := Test_Report{Name: first.Name, Total: s, Lower_Bound: lower_bound}
+		q_values := make([]float64, s)
+		for j, results := range sample_results {
+			result := results[i]
+			if result.Pass(Alpha) {
+				test.Passed++
+			}
+			bin := int(result.P_Value[0] * q_value_bins)
+			test.Histogram[min(max(bin, 0), q_value_bins-1)]++
+			q_values[j] = result.Q_Value[0]
+		}
+		test.Proportion = float64(test.Passed) / float64(s)
+		test.P_Value_T = P_Value_T(q_values)
+		test.Pass = test.Proportion >= lower_bound && test.P_Value_T >= Alpha_T
+		report.Pass = report.Pass && test.Pass
+		report.Tests = append(report.Tests, test)
+	}
+	return report
+}
+
+// 对样本文件(如Get_Sample输出的sample.bin)进行判定
+func Judge_File(path string, config Judge_Config) (Judge_Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Judge_Report{}, err
+	}
+	defer file.Close()
+	return Judge(file, config)
+}
+
+// 判定报告->文本
+func (report Judge_Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "样本个数: %d\n", report.Samples)
+	for _, test := range report.Tests {
+		status := "通过"
+		if !test.Pass {
+			status = "未通过"
+		}
+		fmt.Fprintf(&b, "%s\t%d/%d\tP-value_T=%.6f\t%v\t%s\n", test.Name, test.Passed, test.Total, test.P_Value_T, test.Histogram, status)
+	}
+	if report.Pass {
+		b.WriteString("样本集判定: 通过\n")
+	} else {
+		b.WriteString("样本集判定: 未通过\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package randtest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	Alpha        = 0.01   //显著性水平
+	Alpha_T      = 0.0001 //P-value_T的显著性水平
+	q_value_bins = 10     //P-value_T计算中Q值的分组数
+)
+
+// 比特流,每个元素为0或1
+type Bit_Stream []byte
+
+// 单项检测结果
+type Result struct {
+	Name    string    //检测名称(含参数)
+	P_Value []float64 //P值,部分检测(重叠子序列、累加和)给出多个
+	Q_Value []float64 //Q值,与P值一一对应,用于计算P-value_T
+}
+
+// 检测参数
+type Parameters struct {
+	Block_Frequency_M     int     //块内频数检测的块长度
+	Poker_M               []int   //扑克检测的子序列长度
+	Serial_M              []int   //重叠子序列检测的子序列长度
+	Longest_Run_M         int     //块内最大游程检测的块长度
+	Binary_Derivation_K   []int   //二元推导检测的推导次数
+	Autocorrelation_D     []int   //自相关检测的延迟
+	Matrix_Rank_M         int     //矩阵秩检测的矩阵行数
+	Matrix_Rank_Q         int     //矩阵秩检测的矩阵列数
+	Approximate_Entropy_M []int   //近似熵检测的子序列长度
+	Linear_Complexity_M   []int   //线性复杂度检测的块长度
+	Universal_L           int     //Maurer通用统计检测的块长度
+	Universal_Q           int     //Maurer通用统计检测的初始化块数
+	DFT_Divisor           float64 //离散傅立叶检测方差公式中的除数
+}
+
+// GM/T 0005-2021中10^6比特样本的默认检测参数
+func Default_Parameters() Parameters {
+	return Parameters{
+		Block_Frequency_M:     10000,
+		Poker_M:               []int{4, 8},
+		Serial_M:              []int{3, 5},
+		Longest_Run_M:         10000,
+		Binary_Derivation_K:   []int{3, 7},
+		Autocorrelation_D:     []int{1, 2, 8, 16},
+		Matrix_Rank_M:         32,
+		Matrix_Rank_Q:         32,
+		Approximate_Entropy_M: []int{2, 5},
+		Linear_Complexity_M:   []int{500, 1000},
+		Universal_L:           7,
+		Universal_Q:           1280,
+		DFT_Divisor:           3.8,
+	}
+}
+
+// 字节切片->比特流(高位在前)
+func New_Bit_Stream(bytes []byte) Bit_Stream {
+	stream := make(Bit_Stream, 0, len(bytes)*8)
+	for _, b := range bytes {
+		for j := 7; j >= 0; j-- {
+			stream = append(stream, b>>j&1)
+		}
+	}
+	return stream
+}
+
+// 比特串->比特流,如tools.Bytes2Bits的输出
+func Bits2Stream(bits string) Bit_Stream {
+	stream := make(Bit_Stream, 0, len(bits))
+	for _, c := range bits {
+		switch c {
+		case '0':
+			stream = append(stream, 0)
+		case '1':
+			stream = append(stream, 1)
+		}
+	}
+	return stream
+}
+
+// 判断检测是否通过(全部P值不小于alpha)
+func (result Result) Pass(alpha float64) bool {
+	for _, p := range result.P_Value {
+		if math.IsNaN(p) || p < alpha {
+			return false
+		}
+	}
+	return true
+}
+
+// 由一组样本的Q值计算P-value_T,检验Q值分布的均匀性
+func P_Value_T(q_values []float64) float64 {
+	var F [q_value_bins]float64
+	for _, q := range q_values {
+		i := int(q * q_value_bins)
+		if i >= q_value_bins {
+			i = q_value_bins - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		F[i]++
+	}
+	s := float64(len(q_values))
+	V := 0.0
+	for _, f := range F {
+		V += (f - s/q_value_bins) * (f - s/q_value_bins) / (s / q_value_bins)
+	}
+	return tools.Igamc(float64(q_value_bins-1)/2, V/2)
+}
+
+// 构造单P值的检测结果
+func single_result(name string, P float64, Q float64) Result {
+	return Result{Name: name, P_Value: []float64{P}, Q_Value: []float64{Q}}
+}
+
+// 依次运行GM/T 0005-2021的全部检测
+func Run_All(stream Bit_Stream, parameters Parameters) []Result {
+	results := []Result{
+		Monobit_Frequency(stream),
+		Block_Frequency(stream, parameters.Block_Frequency_M),
+	}
+	for _, m := range parameters.Poker_M {
+		results = append(results, Poker(stream, m))
+	}
+	for _, m := range parameters.Serial_M {
+		results = append(results, Serial(stream, m))
+	}
+	results = append(results,
+		Runs(stream),
+		Runs_Distribution(stream),
+		Longest_Run(stream, parameters.Longest_Run_M))
+	for _, k := range parameters.Binary_Derivation_K {
+		results = append(results, Binary_Derivation(stream, k))
+	}
+	for _, d := range parameters.Autocorrelation_D {
+		results = append(results, Autocorrelation(stream, d))
+	}
+	results = append(results,
+		Matrix_Rank(stream, parameters.Matrix_Rank_M, parameters.Matrix_Rank_Q),
+		Cumulative_Sums(stream))
+	for _, m := range parameters.Approximate_Entropy_M {
+		results = append(results, Approximate_Entropy(stream, m))
+	}
+	for _, m := range parameters.Linear_Complexity_M {
+		results = append(results, Linear_Complexity(stream, m))
+	}
+	results = append(results,
+		Universal(stream, parameters.Universal_L, parameters.Universal_Q),
+		Discrete_Fourier_Transform(stream, parameters.DFT_Divisor))
+	return results
+}
+
+// 打印检测结果
+func Print_Results(results []Result) {
+	for _, result := range results {
+		status := "通过"
+		if !result.Pass(Alpha) {
+			status = "未通过"
+		}
+		fmt.Println(result.Name, result.P_Value, result.Q_Value, status)
+	}
+}
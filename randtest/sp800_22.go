@@ -0,0 +1,246 @@
+package randtest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jellygdh/drbg_sm3/tools"
+)
+
+const (
+	template_blocks          = 8    //非重叠模板匹配检测的块数
+	overlapping_block_length = 1032 //重叠模板匹配检测的块长度
+	overlapping_K            = 5    //重叠模板匹配检测的分类数-1
+)
+
+var excursion_states = []int{-4, -3, -2, -1, 1, 2, 3, 4}                                            //随机游动检测的状态
+var excursion_variant_states = []int{-9, -8, -7, -6, -5, -4, -3, -2, -1, 1, 2, 3, 4, 5, 6, 7, 8, 9} //随机游动状态频数检测的状态
+
+// 长度为m的全部非周期模板(按数值升序),模板的任意真移位都不与自身重叠
+func Aperiodic_Templates(m int) [][]byte {
+	var templates [][]byte
+	for value := 0; value < 1<<m; value++ {
+		template := make([]byte, m)
+		for i := range template {
+			template[i] = byte(value >> (m - 1 - i) & 1)
+		}
+		aperiodic := true
+		for shift := 1; shift < m && aperiodic; shift++ {
+			overlap := true
+			for i := 0; i < m-shift; i++ {
+				if template[i+shift] != template[i] {
+					overlap = false
+					break
+				}
+			}
+			aperiodic = !overlap
+		}
+		if aperiodic {
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}
+
+// 判断比特流在位置i处是否与模板匹配
+func match_template(stream Bit_Stream, i int, template []byte) bool {
+	for j, b := range template {
+		if stream[i+j] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// 非重叠模板匹配检测,对长度为m的每个非周期模板各给出一个P值
+func Non_Overlapping_Template(stream Bit_Stream, m int) Result {
+	templates := Aperiodic_Templates(m)
+	M := len(stream) / template_blocks
+	Mf := float64(M)
+	mu := (Mf - float64(m) + 1) / math.Pow(2, float64(m))
+	variance := Mf * (1/math.Pow(2, float64(m)) - float64(2*m-1)/math.Pow(2, float64(2*m)))
+	result := Result{Name: fmt.Sprintf("非重叠模板匹配检测(m=%d)", m)}
+	for _, template := range templates {
+		chi := 0.0
+		for k := 0; k < template_blocks; k++ {
+			block := stream[k*M : (k+1)*M]
+			W := 0
+			for i := 0; i <= M-m; {
+				if match_template(block, i, template) {
+					W++
+					i += m
+				} else {
+					i++
+				}
+			}
+			chi += (float64(W) - mu) * (float64(W) - mu) / variance
+		}
+		P := tools.Igamc(template_blocks/2.0, chi/2)
+		result.P_Value = append(result.P_Value, P)
+		result.Q_Value = append(result.Q_Value, P)
+	}
+	return result
+}
+
+// 重叠模板匹配检测中模板在块内恰好出现u次的概率
+func overlapping_probability(u int, eta float64) float64 {
+	if u == 0 {
+		return math.Exp(-eta)
+	}
+	p := 0.0
+	for l := 1; l <= u; l++ {
+		lg_u, _ := math.Lgamma(float64(u))
+		lg_l, _ := math.Lgamma(float64(l))
+		lg_l1, _ := math.Lgamma(float64(l + 1))
+		lg_ul, _ := math.Lgamma(float64(u - l + 1))
+		p += math.Exp(-eta - float64(u)*math.Ln2 + float64(l)*math.Log(eta) - lg_l1 + lg_u - lg_l - lg_ul)
+	}
+	return p
+}
+
+// 重叠模板匹配检测,模板为长度m的全1序列
+func Overlapping_Template(stream Bit_Stream, m int) Result {
+	M := overlapping_block_length
+	N := len(stream) / M
+	template := make([]byte, m)
+	for i := range template {
+		template[i] = 1
+	}
+	observed := make([]float64, overlapping_K+1)
+	for k := 0; k < N; k++ {
+		block := stream[k*M : (k+1)*M]
+		W := 0
+		for i := 0; i <= M-m; i++ {
+			if match_template(block, i, template) {
+				W++
+			}
+		}
+		observed[min(W, overlapping_K)]++
+	}
+	eta := (float64(M-m+1) / math.Pow(2, float64(m))) / 2
+	expected := make([]float64, overlapping_K+1)
+	sum := 0.0
+	for i := 0; i < overlapping_K; i++ {
+		pi := overlapping_probability(i, eta)
+		expected[i] = float64(N) * pi
+		sum += pi
+	}
+	expected[overlapping_K] = float64(N) * (1 - sum)
+	P := tools.Igamc(overlapping_K/2.0, chi_square(observed, expected)/2)
+	return single_result(fmt.Sprintf("重叠模板匹配检测(m=%d)", m), P, P)
+}
+
+// 随机游动的各个循环,每个循环为相邻两次回到0之间的累加和序列
+func excursion_cycles(stream Bit_Stream) [][]int {
+	var cycles [][]int
+	var cycle []int
+	S := 0
+	for _, b := range stream {
+		S += 2*int(b) - 1
+		if S == 0 {
+			cycles = append(cycles, cycle)
+			cycle = nil
+		} else {
+			cycle = append(cycle, S)
+		}
+	}
+	//末尾未回到0时,最后一段同样视为一个循环
+	if S != 0 {
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+// 随机游动检测的循环数下限,低于下限时检测不适用
+func excursion_min_cycles(n int) int {
+	return max(500, int(0.005*math.Sqrt(float64(n))))
+}
+
+// 随机游动中状态x在一个循环内恰好出现k次(k=5表示不少于5次)的概率
+func excursion_probability(k int, x int) float64 {
+	ax := math.Abs(float64(x))
+	switch {
+	case k == 0:
+		return 1 - 1/(2*ax)
+	case k < 5:
+		return 1 / (4 * ax * ax) * math.Pow(1-1/(2*ax), float64(k-1))
+	default:
+		return 1 / (2 * ax) * math.Pow(1-1/(2*ax), 4)
+	}
+}
+
+// 随机游动检测,对状态-4..-1,1..4各给出一个P值;循环数不足时检测不适用,不给出P值
+func Random_Excursions(stream Bit_Stream) Result {
+	result := Result{Name: "随机游动检测"}
+	cycles := excursion_cycles(stream)
+	J := len(cycles)
+	if J < excursion_min_cycles(len(stream)) {
+		result.Name += "(不适用)"
+		return result
+	}
+	for _, x := range excursion_states {
+		observed := make([]float64, 6)
+		for _, cycle := range cycles {
+			visits := 0
+			for _, S := range cycle {
+				if S == x {
+					visits++
+				}
+			}
+			observed[min(visits, 5)]++
+		}
+		expected := make([]float64, 6)
+		for k := range expected {
+			expected[k] = float64(J) * excursion_probability(k, x)
+		}
+		P := tools.Igamc(2.5, chi_square(observed, expected)/2)
+		result.P_Value = append(result.P_Value, P)
+		result.Q_Value = append(result.Q_Value, P)
+	}
+	return result
+}
+
+// 随机游动状态频数检测,对状态-9..-1,1..9各给出一个P值;循环数不足时检测不适用,不给出P值
+func Random_Excursions_Variant(stream Bit_Stream) Result {
+	result := Result{Name: "随机游动状态频数检测"}
+	cycles := excursion_cycles(stream)
+	J := float64(len(cycles))
+	if len(cycles) < excursion_min_cycles(len(stream)) {
+		result.Name += "(不适用)"
+		return result
+	}
+	counts := make(map[int]int)
+	for _, cycle := range cycles {
+		for _, S := range cycle {
+			counts[S]++
+		}
+	}
+	for _, x := range excursion_variant_states {
+		ax := math.Abs(float64(x))
+		P := math.Erfc(math.Abs(float64(counts[x])-J) / math.Sqrt(2*J*(4*ax-2)))
+		result.P_Value = append(result.P_Value, P)
+		result.Q_Value = append(result.Q_Value, P)
+	}
+	return result
+}
+
+// 依次运行SP 800-22的全部检测(采用NIST参考实现的默认参数)
+func Run_SP800_22(stream Bit_Stream) []Result {
+	return []Result{
+		Monobit_Frequency(stream),
+		Block_Frequency(stream, 128),
+		Cumulative_Sums(stream),
+		Runs(stream),
+		Longest_Run(stream, 10000),
+		Matrix_Rank(stream, 32, 32),
+		Discrete_Fourier_Transform(stream, 4),
+		Non_Overlapping_Template(stream, 9),
+		Overlapping_Template(stream, 9),
+		Universal(stream, 7, 1280),
+		Approximate_Entropy(stream, 10),
+		Random_Excursions(stream),
+		Random_Excursions_Variant(stream),
+		Linear_Complexity(stream, 500),
+		Serial(stream, 16),
+	}
+}
@@ -0,0 +1,295 @@
+// Package sm2 实现GM/T 0003-2012 SM2椭圆曲线数字签名算法,随机数取自SM3 DRBG
+package sm2
+
+import (
+	"math/big"
+	"sync"
+)
+
+// 素域Fp上的椭圆曲线y^2=x^3+ax+b
+type Curve struct {
+	Name    string   //曲线名称
+	P       *big.Int //素数p
+	A       *big.Int //系数a
+	B       *big.Int //系数b
+	N       *big.Int //基点的阶n
+	Gx      *big.Int //基点横坐标
+	Gy      *big.Int //基点纵坐标
+	BitSize int      //p的比特长度
+
+	once    sync.Once     //定时运算参数的延迟初始化
+	fp      *field        //模p的定时运算
+	fn      *field        //模n的定时运算
+	a_mont  field_element //a的蒙哥马利形式
+	b3_mont field_element //3b的蒙哥马利形式
+}
+
+// 定时运算参数(首次使用时计算)
+func (curve *Curve) constant_time() {
+	curve.once.Do(func() {
+		curve.fp = new_field(curve.P)
+		curve.fn = new_field(curve.N)
+		curve.a_mont = curve.fp.from_big(curve.A)
+		curve.b3_mont = curve.fp.from_big(new(big.Int).Mod(new(big.Int).Mul(curve.B, big.NewInt(3)), curve.P))
+	})
+}
+
+// 十六进制常量->big.Int
+func from_hex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("sm2: invalid constant " + s)
+	}
+	return n
+}
+
+// GM/T 0003.5推荐曲线sm2p256v1
+var sm2p256v1 = &Curve{
+	Name:    "sm2p256v1",
+	P:       from_hex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF"),
+	A:       from_hex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFC"),
+	B:       from_hex("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93"),
+	N:       from_hex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123"),
+	Gx:      from_hex("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7"),
+	Gy:      from_hex("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0"),
+	BitSize: 256,
+}
+
+// GM/T 0003.2附录A的素域256比特示例曲线,仅用于测试向量
+var example_curve = &Curve{
+	Name:    "GM/T 0003.2 Fp-256 example",
+	P:       from_hex("8542D69E4C044F18E8B92435BF6FF7DE457283915C45517D722EDB8B08F1DFC3"),
+	A:       from_hex("787968B4FA32C3FD2417842E73BBFEFF2F3C848B6831D7E0EC65228B3937E498"),
+	B:       from_hex("63E4C6D3B23B0C849CF84241484BFE48F61D59A5B16BA06E6E12D1DA27C5249A"),
+	N:       from_hex("8542D69E4C044F18E8B92435BF6FF7DD297720630485628D5AE74EE7C32E79B7"),
+	Gx:      from_hex("421DEBD61B62EAB6746434EBC3CC315E32220B3BADD50BDC4C4E6C147FEDD43D"),
+	Gy:      from_hex("0680512BCBB42C07D47349D2153B70C4E5D7FDFCBFA36EA1A85841B9E46E09A2"),
+	BitSize: 256,
+}
+
+// 推荐曲线sm2p256v1
+func P256() *Curve {
+	return sm2p256v1
+}
+
+// GM/T 0003.2附录A示例曲线,仅用于测试
+func Example_Curve() *Curve {
+	return example_curve
+}
+
+// 雅可比坐标下的点(X/Z^2, Y/Z^3),Z为0时为无穷远点
+type jacobian struct {
+	X, Y, Z *big.Int
+}
+
+// a mod p
+func (curve *Curve) mod(a *big.Int) *big.Int {
+	return a.Mod(a, curve.P)
+}
+
+// 判断仿射坐标点是否在曲线上
+func (curve *Curve) Is_On_Curve(x *big.Int, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(curve.P) >= 0 || y.Sign() < 0 || y.Cmp(curve.P) >= 0 {
+		return false
+	}
+	left := curve.mod(new(big.Int).Mul(y, y))
+	right := new(big.Int).Mul(x, x)
+	right.Add(right, curve.A)
+	right.Mul(right, x)
+	right.Add(right, curve.B)
+	return left.Cmp(curve.mod(right)) == 0
+}
+
+// 倍点:M=3X^2+aZ^4, S=4XY^2, X3=M^2-2S, Y3=M(S-X3)-8Y^4, Z3=2YZ
+func (curve *Curve) double(point jacobian) jacobian {
+	if point.Z.Sign() == 0 || point.Y.Sign() == 0 {
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	YY := curve.mod(new(big.Int).Mul(point.Y, point.Y))
+	ZZ := curve.mod(new(big.Int).Mul(point.Z, point.Z))
+	S := new(big.Int).Mul(point.X, YY)
+	S = curve.mod(S.Lsh(S, 2))
+	M := new(big.Int).Mul(point.X, point.X)
+	M.Mul(M, big.NewInt(3))
+	aZZZZ := new(big.Int).Mul(ZZ, ZZ)
+	aZZZZ.Mul(aZZZZ, curve.A)
+	M = curve.mod(M.Add(M, aZZZZ))
+	X3 := new(big.Int).Mul(M, M)
+	X3.Sub(X3, S)
+	X3 = curve.mod(X3.Sub(X3, S))
+	YYYY := new(big.Int).Mul(YY, YY)
+	Y3 := new(big.Int).Sub(S, X3)
+	Y3.Mul(Y3, M)
+	Y3 = curve.mod(Y3.Sub(Y3, YYYY.Lsh(YYYY, 3)))
+	Z3 := new(big.Int).Mul(point.Y, point.Z)
+	Z3 = curve.mod(Z3.Lsh(Z3, 1))
+	return jacobian{X3, Y3, Z3}
+}
+
+// 点加:U1=X1Z2^2, U2=X2Z1^2, S1=Y1Z2^3, S2=Y2Z1^3, H=U2-U1, R=S2-S1,
+// X3=R^2-H^3-2U1H^2, Y3=R(U1H^2-X3)-S1H^3, Z3=HZ1Z2
+func (curve *Curve) add(p1 jacobian, p2 jacobian) jacobian {
+	if p1.Z.Sign() == 0 {
+		return p2
+	}
+	if p2.Z.Sign() == 0 {
+		return p1
+	}
+	Z1Z1 := curve.mod(new(big.Int).Mul(p1.Z, p1.Z))
+	Z2Z2 := curve.mod(new(big.Int).Mul(p2.Z, p2.Z))
+	U1 := curve.mod(new(big.Int).Mul(p1.X, Z2Z2))
+	U2 := curve.mod(new(big.Int).Mul(p2.X, Z1Z1))
+	S1 := new(big.Int).Mul(p1.Y, p2.Z)
+	S1 = curve.mod(S1.Mul(S1, Z2Z2))
+	S2 := new(big.Int).Mul(p2.Y, p1.Z)
+	S2 = curve.mod(S2.Mul(S2, Z1Z1))
+	if U1.Cmp(U2) == 0 {
+		if S1.Cmp(S2) == 0 {
+			return curve.double(p1)
+		}
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	H := curve.mod(new(big.Int).Sub(U2, U1))
+	R := curve.mod(new(big.Int).Sub(S2, S1))
+	HH := curve.mod(new(big.Int).Mul(H, H))
+	HHH := curve.mod(new(big.Int).Mul(HH, H))
+	U1HH := curve.mod(new(big.Int).Mul(U1, HH))
+	X3 := new(big.Int).Mul(R, R)
+	X3.Sub(X3, HHH)
+	X3.Sub(X3, U1HH)
+	X3 = curve.mod(X3.Sub(X3, U1HH))
+	Y3 := new(big.Int).Sub(U1HH, X3)
+	Y3.Mul(Y3, R)
+	Y3 = curve.mod(Y3.Sub(Y3, S1.Mul(S1, HHH)))
+	Z3 := new(big.Int).Mul(H, p1.Z)
+	Z3 = curve.mod(Z3.Mul(Z3, p2.Z))
+	return jacobian{X3, Y3, Z3}
+}
+
+// 仿射坐标->雅可比坐标
+func to_jacobian(x *big.Int, y *big.Int) jacobian {
+	return jacobian{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+// 雅可比坐标->仿射坐标,无穷远点返回(0,0)
+func (curve *Curve) to_affine(point jacobian) (*big.Int, *big.Int) {
+	if point.Z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	Z_inverse := new(big.Int).ModInverse(point.Z, curve.P)
+	Z_inverse_2 := curve.mod(new(big.Int).Mul(Z_inverse, Z_inverse))
+	x := curve.mod(new(big.Int).Mul(point.X, Z_inverse_2))
+	y := curve.mod(new(big.Int).Mul(point.Y, Z_inverse_2.Mul(Z_inverse_2, Z_inverse)))
+	return x, y
+}
+
+// 多倍点运算[k](x,y)(变时),结果为无穷远点时返回(0,0)。
+// 基于math/big,运算时间与k有关,仅用于验签等标量公开的场合
+func (curve *Curve) scalar_mult_vartime(x *big.Int, y *big.Int, k *big.Int) (*big.Int, *big.Int) {
+	base := to_jacobian(x, y)
+	result := jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = curve.double(result)
+		if k.Bit(i) == 1 {
+			result = curve.add(result, base)
+		}
+	}
+	return curve.to_affine(result)
+}
+
+// 射影坐标下的点(X/Z, Y/Z),无穷远点为(0:1:0)
+type projective struct {
+	X, Y, Z field_element
+}
+
+// 完全加法公式(Renes-Costello-Batina 2016,算法1,适用于任意a),
+// 对P+Q、P+P、P+O均成立,运算序列与点的取值无关
+func (curve *Curve) complete_add(p1 projective, p2 projective) projective {
+	f := curve.fp
+	t0 := f.mul(p1.X, p2.X)
+	t1 := f.mul(p1.Y, p2.Y)
+	t2 := f.mul(p1.Z, p2.Z)
+	t3 := f.mul(f.add(p1.X, p1.Y), f.add(p2.X, p2.Y))
+	t4 := f.add(t0, t1)
+	t3 = f.sub(t3, t4)
+	t4 = f.mul(f.add(p1.X, p1.Z), f.add(p2.X, p2.Z))
+	t5 := f.add(t0, t2)
+	t4 = f.sub(t4, t5)
+	t5 = f.mul(f.add(p1.Y, p1.Z), f.add(p2.Y, p2.Z))
+	X3 := f.add(t1, t2)
+	t5 = f.sub(t5, X3)
+	Z3 := f.mul(curve.a_mont, t4)
+	X3 = f.mul(curve.b3_mont, t2)
+	Z3 = f.add(X3, Z3)
+	X3 = f.sub(t1, Z3)
+	Z3 = f.add(t1, Z3)
+	Y3 := f.mul(X3, Z3)
+	t1 = f.add(t0, t0)
+	t1 = f.add(t1, t0)
+	t2 = f.mul(curve.a_mont, t2)
+	t4 = f.mul(curve.b3_mont, t4)
+	t1 = f.add(t1, t2)
+	t2 = f.sub(t0, t2)
+	t2 = f.mul(curve.a_mont, t2)
+	t4 = f.add(t4, t2)
+	t0 = f.mul(t1, t4)
+	Y3 = f.add(Y3, t0)
+	t0 = f.mul(t5, t4)
+	X3 = f.mul(t3, X3)
+	X3 = f.sub(X3, t0)
+	t0 = f.mul(t3, t1)
+	Z3 = f.mul(t5, Z3)
+	Z3 = f.add(Z3, t0)
+	return projective{X3, Y3, Z3}
+}
+
+// bit=1时交换两点
+func projective_swap(p1 *projective, p2 *projective, bit uint64) {
+	conditional_swap(&p1.X, &p2.X, bit)
+	conditional_swap(&p1.Y, &p2.Y, bit)
+	conditional_swap(&p1.Z, &p2.Z, bit)
+}
+
+// 多倍点运算[k](x,y),结果为无穷远点时返回(0,0)。
+// 蒙哥马利阶梯,固定迭代n的比特长度次,每次迭代执行一次条件交换与两次完全加法,
+// 运算时间与k无关,用于私钥d、随机数k等秘密标量。k不在[0,n-1]内时先约化(变时)
+func (curve *Curve) Scalar_Mult(x *big.Int, y *big.Int, k *big.Int) (*big.Int, *big.Int) {
+	curve.constant_time()
+	f := curve.fp
+	if k.Sign() < 0 || k.Cmp(curve.N) >= 0 {
+		k = new(big.Int).Mod(k, curve.N)
+	}
+	scalar := big_to_limbs(k)
+	infinity := projective{field_element{}, f.one, field_element{}}
+	r0, r1 := infinity, infinity
+	if x.Sign() != 0 || y.Sign() != 0 {
+		r1 = projective{f.from_big(new(big.Int).Mod(x, curve.P)), f.from_big(new(big.Int).Mod(y, curve.P)), f.one}
+	}
+	for i := curve.N.BitLen() - 1; i >= 0; i-- {
+		bit := scalar[i/64] >> (i % 64) & 1
+		projective_swap(&r0, &r1, bit)
+		r1 = curve.complete_add(r0, r1)
+		r0 = curve.complete_add(r0, r0)
+		projective_swap(&r0, &r1, bit)
+	}
+	//Z=0时Z^-1=0,无穷远点得到(0,0)
+	Z_inverse := f.inverse(r0.Z)
+	return f.to_big(f.mul(r0.X, Z_inverse)), f.to_big(f.mul(r0.Y, Z_inverse))
+}
+
+// 基点多倍点运算[k]G,运算时间与k无关
+func (curve *Curve) Scalar_Base_Mult(k *big.Int) (*big.Int, *big.Int) {
+	return curve.Scalar_Mult(curve.Gx, curve.Gy, k)
+}
+
+// 点加运算,任一点为(0,0)时视为无穷远点
+func (curve *Curve) Add(x1 *big.Int, y1 *big.Int, x2 *big.Int, y2 *big.Int) (*big.Int, *big.Int) {
+	p1, p2 := to_jacobian(x1, y1), to_jacobian(x2, y2)
+	if x1.Sign() == 0 && y1.Sign() == 0 {
+		p1.Z.SetInt64(0)
+	}
+	if x2.Sign() == 0 && y2.Sign() == 0 {
+		p2.Z.SetInt64(0)
+	}
+	return curve.to_affine(curve.add(p1, p2))
+}
@@ -0,0 +1,116 @@
+package sm2
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+	"slices"
+
+	"github.com/jellygdh/drbg_sm3/sm3"
+)
+
+const hedged_entropy_length = 32 //对冲签名混入的新鲜随机数长度(单位:字节)
+
+// RFC 6979第3.2节的确定性随机数生成器(HMAC_DRBG),适配SM2与SM3
+type nonce_generator struct {
+	new_hash func() hash.Hash //杂凑函数,SM2使用SM3
+	n        *big.Int         //基点的阶
+	K, V     []byte
+}
+
+// bits2int:取比特串的前qlen比特作为整数
+func bits2int(b []byte, n *big.Int) *big.Int {
+	x := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - n.BitLen(); excess > 0 {
+		x.Rsh(x, uint(excess))
+	}
+	return x
+}
+
+// int2octets:整数->rlen字节的定长串
+func int2octets(x *big.Int, n *big.Int) []byte {
+	return x.FillBytes(make([]byte, (n.BitLen()+7)/8))
+}
+
+// bits2octets:bits2int(b) mod n->rlen字节的定长串
+func bits2octets(b []byte, n *big.Int) []byte {
+	z := bits2int(b, n)
+	if z.Cmp(n) >= 0 {
+		z.Sub(z, n)
+	}
+	return int2octets(z, n)
+}
+
+func (generator *nonce_generator) mac(data ...[]byte) []byte {
+	mac := hmac.New(generator.new_hash, generator.K)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// 由私钥x、消息杂凑h1与附加数据extra初始化(RFC 6979第3.2节步骤b至g,第3.6节附加数据)
+func new_nonce_generator(new_hash func() hash.Hash, n *big.Int, x *big.Int, h1 []byte, extra []byte) *nonce_generator {
+	size := new_hash().Size()
+	generator := &nonce_generator{new_hash: new_hash, n: n, K: make([]byte, size), V: make([]byte, size)}
+	for i := range generator.V {
+		generator.V[i] = 0x01
+	}
+	seed := slices.Concat(int2octets(x, n), bits2octets(h1, n), extra)
+	generator.K = generator.mac(generator.V, []byte{0x00}, seed)
+	generator.V = generator.mac(generator.V)
+	generator.K = generator.mac(generator.V, []byte{0x01}, seed)
+	generator.V = generator.mac(generator.V)
+	return generator
+}
+
+// 输出下一个[1,n-1]内的候选k(RFC 6979第3.2节步骤h)
+func (generator *nonce_generator) next() *big.Int {
+	for {
+		var T []byte
+		for len(T)*8 < generator.n.BitLen() {
+			generator.V = generator.mac(generator.V)
+			T = append(T, generator.V...)
+		}
+		k := bits2int(T, generator.n)
+		generator.K = generator.mac(generator.V, []byte{0x00})
+		generator.V = generator.mac(generator.V)
+		if k.Sign() > 0 && k.Cmp(generator.n) < 0 {
+			return k
+		}
+	}
+}
+
+// 以HMAC-SM3由私钥与消息杂凑e派生k并签名,extra为附加数据(可为空)
+func (private_key *Private_Key) sign_derived(id []byte, message []byte, extra []byte) (*big.Int, *big.Int, error) {
+	e, err := private_key.digest(id, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := private_key.Curve.N
+	generator := new_nonce_generator(sm3.New, n, private_key.D, int2octets(e, n), extra)
+	for {
+		if r, s, ok := private_key.sign_with_k(e, generator.next()); ok {
+			return r, s, nil
+		}
+	}
+}
+
+// 确定性签名:k由HMAC-SM3按RFC 6979从私钥与e=SM3(Z||M)派生,签名不依赖随机数发生器,
+// 相同私钥、用户标识与消息总是得到相同签名
+func Sign_Deterministic(private_key *Private_Key, id []byte, message []byte) (*big.Int, *big.Int, error) {
+	return private_key.sign_derived(id, message, nil)
+}
+
+// 对冲签名:在确定性签名的基础上,从rand读取hedged_entropy_length字节作为RFC 6979第3.6节的附加数据。
+// 熵池退化时安全性不低于确定性签名,随机数发生器正常时可抵御针对确定性签名的故障攻击。
+// rand应为SM3 DRBG(*drbg.Working_State),不宜对每次签名使用Critical()
+func Sign_Hedged(rand io.Reader, private_key *Private_Key, id []byte, message []byte) (*big.Int, *big.Int, error) {
+	extra := make([]byte, hedged_entropy_length)
+	if _, err := io.ReadFull(rand, extra); err != nil {
+		return nil, nil, errors.New("Sign_Hedged error: " + err.Error())
+	}
+	return private_key.sign_derived(id, message, extra)
+}
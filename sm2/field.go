@@ -0,0 +1,169 @@
+package sm2
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// 256比特以内奇素数模数下的元素,蒙哥马利形式a·R mod m(R=2^256),4个64比特字,小端序
+type field_element [4]uint64
+
+// 模m的定时运算:运算过程不依赖元素的取值,仅依赖模数(公开参数)。
+// 用于私钥d、随机数k等秘密值参与的多倍点运算与模n运算
+type field struct {
+	modulus field_element //模数m
+	m0_inv  uint64        //-m^-1 mod 2^64
+	r2      field_element //R^2 mod m
+	one     field_element //R mod m,即1的蒙哥马利形式
+	exp     field_element //m-2,求逆所用的指数
+}
+
+// big.Int(非负,小于2^256)->字,不按元素值提前结束
+func big_to_limbs(x *big.Int) field_element {
+	var buffer [32]byte
+	x.FillBytes(buffer[:])
+	var limbs field_element
+	for i := range limbs {
+		for j := 0; j < 8; j++ {
+			limbs[i] |= uint64(buffer[31-8*i-j]) << (8 * j)
+		}
+	}
+	return limbs
+}
+
+// 字->big.Int
+func limbs_to_big(limbs field_element) *big.Int {
+	var buffer [32]byte
+	for i := range limbs {
+		for j := 0; j < 8; j++ {
+			buffer[31-8*i-j] = byte(limbs[i] >> (8 * j))
+		}
+	}
+	return new(big.Int).SetBytes(buffer[:])
+}
+
+// 创建模m的定时运算,m须为小于2^256的奇数
+func new_field(m *big.Int) *field {
+	f := &field{modulus: big_to_limbs(m)}
+	inverse := uint64(1)
+	for i := 0; i < 6; i++ {
+		inverse *= 2 - f.modulus[0]*inverse
+	}
+	f.m0_inv = -inverse
+	R := new(big.Int).Lsh(big.NewInt(1), 256)
+	f.one = big_to_limbs(new(big.Int).Mod(R, m))
+	f.r2 = big_to_limbs(new(big.Int).Mod(new(big.Int).Mul(R, R), m))
+	f.exp = big_to_limbs(new(big.Int).Sub(m, big.NewInt(2)))
+	return f
+}
+
+// 对x||high(x<2m)进行一次条件减法
+func (f *field) reduce(x field_element, high uint64) field_element {
+	var d field_element
+	var borrow uint64
+	for i := range x {
+		d[i], borrow = bits.Sub64(x[i], f.modulus[i], borrow)
+	}
+	//high=1或未借位时x>=m,取x-m
+	mask := -(high | (borrow ^ 1))
+	for i := range x {
+		d[i] = d[i]&mask | x[i]&^mask
+	}
+	return d
+}
+
+// a+b mod m
+func (f *field) add(a field_element, b field_element) field_element {
+	var sum field_element
+	var carry uint64
+	for i := range a {
+		sum[i], carry = bits.Add64(a[i], b[i], carry)
+	}
+	return f.reduce(sum, carry)
+}
+
+// a-b mod m
+func (f *field) sub(a field_element, b field_element) field_element {
+	var d field_element
+	var borrow uint64
+	for i := range a {
+		d[i], borrow = bits.Sub64(a[i], b[i], borrow)
+	}
+	mask := -borrow
+	var carry uint64
+	for i := range d {
+		d[i], carry = bits.Add64(d[i], f.modulus[i]&mask, carry)
+	}
+	return d
+}
+
+// 蒙哥马利乘法a·b·R^-1 mod m(CIOS)
+func (f *field) mul(a field_element, b field_element) field_element {
+	var t [6]uint64
+	for i := 0; i < 4; i++ {
+		var c, carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[j], b[i])
+			lo, carry = bits.Add64(lo, t[j], 0)
+			hi += carry
+			lo, carry = bits.Add64(lo, c, 0)
+			hi += carry
+			t[j], c = lo, hi
+		}
+		t[4], carry = bits.Add64(t[4], c, 0)
+		t[5] = carry
+
+		m := t[0] * f.m0_inv
+		hi, lo := bits.Mul64(m, f.modulus[0])
+		_, carry = bits.Add64(lo, t[0], 0)
+		c = hi + carry
+		for j := 1; j < 4; j++ {
+			hi, lo = bits.Mul64(m, f.modulus[j])
+			lo, carry = bits.Add64(lo, t[j], 0)
+			hi += carry
+			lo, carry = bits.Add64(lo, c, 0)
+			hi += carry
+			t[j-1], c = lo, hi
+		}
+		t[3], carry = bits.Add64(t[4], c, 0)
+		t[4] = t[5] + carry
+	}
+	return f.reduce(field_element{t[0], t[1], t[2], t[3]}, t[4])
+}
+
+// 求逆a^(m-2),指数为公开参数,运算序列与a无关;a=0时返回0
+func (f *field) inverse(a field_element) field_element {
+	result := f.one
+	for i := 255; i >= 0; i-- {
+		result = f.mul(result, result)
+		if f.exp[i/64]>>(i%64)&1 == 1 {
+			result = f.mul(result, a)
+		}
+	}
+	return result
+}
+
+// 普通整数(小于m)->蒙哥马利形式
+func (f *field) from_big(x *big.Int) field_element {
+	return f.mul(big_to_limbs(x), f.r2)
+}
+
+// 蒙哥马利形式->普通整数
+func (f *field) to_big(a field_element) *big.Int {
+	return limbs_to_big(f.mul(a, field_element{1}))
+}
+
+// 判断是否为0,不按元素值提前结束
+func (f *field) is_zero(a field_element) bool {
+	return a[0]|a[1]|a[2]|a[3] == 0
+}
+
+// bit=1时交换a与b,bit须为0或1
+func conditional_swap(a *field_element, b *field_element, bit uint64) {
+	mask := -bit
+	for i := range a {
+		t := (a[i] ^ b[i]) & mask
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
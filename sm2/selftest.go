@@ -0,0 +1,140 @@
+package sm2
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/jellygdh/drbg_sm3/drbg"
+	"github.com/jellygdh/drbg_sm3/random"
+)
+
+// GM/T 0003.2附录A.2示例:素域256比特曲线上的数字签名
+const (
+	example_d  = "128B2FA8BD433C6C068C8D803DFF79792A519A55171B1B650C23661D15897263"
+	example_Px = "0AE4C7798AA0F119471BEE11825BE46202BB79E2A5844495E97C04FF4DF2548A"
+	example_Py = "7C0240F88F1CD4E16352A73C17B7F16F07353E53A176D684A9FE0C6BB798E857"
+	example_Z  = "F4A38489E32B45B6F876E3AC2168CA392362DC8F23459C1D1146FC3DBFB7BC9A"
+	example_k  = "6CB28D99385C175C94F94E934817663FC176D925DD72B727260DBAAE1FB2F96F"
+	example_r  = "40F1EC59F793D9F49E09DCEF49130D4194F79FB1EED2CAA55BACDB49C4E755D1"
+	example_s  = "6FC6DAC32C5D5CF10C77DFB20F7C2EB667A457872FB09EC56327A67EC7DEEBE7"
+)
+
+var example_id = []byte("ALICE123@YAHOO.COM")
+var example_message = []byte("message digest")
+
+// RFC 6979附录A.2.5:P-256,SHA-256,消息"sample",用于检验确定性随机数生成器本身
+const (
+	rfc6979_q = "FFFFFFFF00000000FFFFFFFFFFFFFFFFBCE6FAADA7179E84F3B9CAC2FC632551"
+	rfc6979_x = "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721"
+	rfc6979_k = "A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60"
+)
+
+// 确定性签名自检:以RFC 6979的P-256/SHA-256向量检验随机数派生过程;
+// 在GM/T 0003.2示例曲线上检查确定性签名可验证且可复现、不同消息得到不同签名,
+// 对冲签名可验证且每次不同
+func Test_Deterministic() int {
+	h1 := sha256.Sum256([]byte("sample"))
+	k := new_nonce_generator(sha256.New, from_hex(rfc6979_q), from_hex(rfc6979_x), h1[:], nil).next()
+	if k.Cmp(from_hex(rfc6979_k)) != 0 {
+		return -1
+	}
+
+	private_key, _ := New_Private_Key(Example_Curve(), from_hex(example_d))
+	r1, s1, err1 := Sign_Deterministic(private_key, example_id, example_message)
+	r2, s2, err2 := Sign_Deterministic(private_key, example_id, example_message)
+	r3, _, err3 := Sign_Deterministic(private_key, example_id, []byte("message digesT"))
+	if err1 != nil || err2 != nil || err3 != nil || r1.Cmp(r2) != 0 || s1.Cmp(s2) != 0 || r1.Cmp(r3) == 0 {
+		return -1
+	}
+	if !Verify(&private_key.Public_Key, example_id, example_message, r1, s1) {
+		return -1
+	}
+
+	seed := []byte("sm2 hedged self-test")
+	rand := drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil)
+	r4, s4, err4 := Sign_Hedged(rand, private_key, example_id, example_message)
+	r5, s5, err5 := Sign_Hedged(rand, private_key, example_id, example_message)
+	if err4 != nil || err5 != nil || r4.Cmp(r5) == 0 || r4.Cmp(r1) == 0 {
+		return -1
+	}
+	if !Verify(&private_key.Public_Key, example_id, example_message, r4, s4) || !Verify(&private_key.Public_Key, example_id, example_message, r5, s5) {
+		return -1
+	}
+	return 0
+}
+
+// 测试向量自检:GM/T 0003.2示例的公钥、Z值、签名与验证;
+// sm2p256v1上以固定种子的SM3 Hash_DRBG生成密钥并签名、验证,篡改消息后验证失败
+func Test_Vectors() int {
+	curve := Example_Curve()
+	private_key, err := New_Private_Key(curve, from_hex(example_d))
+	if err != nil || private_key.X.Cmp(from_hex(example_Px)) != 0 || private_key.Y.Cmp(from_hex(example_Py)) != 0 {
+		return -1
+	}
+	Z, _ := private_key.Z(example_id)
+	expected_Z, _ := hex.DecodeString(example_Z)
+	if !bytes.Equal(Z, expected_Z) {
+		return -1
+	}
+	e, _ := private_key.digest(example_id, example_message)
+	r, s, ok := private_key.sign_with_k(e, from_hex(example_k))
+	if !ok || r.Cmp(from_hex(example_r)) != 0 || s.Cmp(from_hex(example_s)) != 0 {
+		return -1
+	}
+	if !Verify(&private_key.Public_Key, example_id, example_message, r, s) {
+		return -1
+	}
+
+	seed := []byte("sm2 self-test")
+	rand := bufio.NewReader(drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil))
+	private_key, err = Generate_Key(P256(), rand)
+	if err != nil || !P256().Is_On_Curve(private_key.X, private_key.Y) {
+		return -1
+	}
+	r, s, err = Sign(rand, private_key, Default_ID, example_message)
+	if err != nil || !Verify(&private_key.Public_Key, Default_ID, example_message, r, s) {
+		return -1
+	}
+	if Verify(&private_key.Public_Key, Default_ID, []byte("message digesT"), r, s) || Verify(&private_key.Public_Key, Default_ID, example_message, s, r) {
+		return -1
+	}
+	if Verify(&private_key.Public_Key, Default_ID, example_message, r, new(big.Int).Add(s, P256().N)) {
+		return -1
+	}
+	return 0
+}
+
+// 多倍点运算自检:在两条曲线上以蒙哥马利阶梯与变时实现分别计算[k]G与[k]P,结果应一致,
+// k取0、1、2、n-1、n及固定种子的SM3 Hash_DRBG生成的随机值;[k](0,0)应为(0,0)
+func Test_Scalar_Mult() int {
+	seed := []byte("sm2 scalar self-test")
+	generator := random.New(bufio.NewReader(drbg.New_Hash_DRBG(drbg.SM3_Hash, seed, seed, nil)))
+	for _, curve := range []*Curve{P256(), Example_Curve()} {
+		one := big.NewInt(1)
+		scalars := []*big.Int{big.NewInt(0), one, big.NewInt(2), new(big.Int).Sub(curve.N, one), curve.N}
+		for i := 0; i < 16; i++ {
+			k, err := generator.Big_Int_Range(one, new(big.Int).Sub(curve.N, one))
+			if err != nil {
+				return -1
+			}
+			scalars = append(scalars, k)
+		}
+		Px, Py := curve.scalar_mult_vartime(curve.Gx, curve.Gy, scalars[len(scalars)-1])
+		for _, k := range scalars {
+			x1, y1 := curve.Scalar_Base_Mult(k)
+			x2, y2 := curve.scalar_mult_vartime(curve.Gx, curve.Gy, k)
+			x3, y3 := curve.Scalar_Mult(Px, Py, k)
+			x4, y4 := curve.scalar_mult_vartime(Px, Py, k)
+			if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 || x3.Cmp(x4) != 0 || y3.Cmp(y4) != 0 {
+				return -1
+			}
+			if x, y := curve.Scalar_Mult(new(big.Int), new(big.Int), k); x.Sign() != 0 || y.Sign() != 0 {
+				return -1
+			}
+		}
+	}
+	return 0
+}
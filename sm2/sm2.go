@@ -0,0 +1,145 @@
+package sm2
+
+import (
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/jellygdh/drbg_sm3/random"
+	"github.com/jellygdh/drbg_sm3/sm3"
+)
+
+var Default_ID = []byte("1234567812345678") //GM/T 0009规定的默认用户标识
+
+// SM2公钥
+type Public_Key struct {
+	Curve *Curve
+	X, Y  *big.Int
+}
+
+// SM2私钥
+type Private_Key struct {
+	Public_Key
+	D *big.Int
+}
+
+// 由私钥d计算公钥,d须在[1,n-2]内
+func New_Private_Key(curve *Curve, d *big.Int) (*Private_Key, error) {
+	upper := new(big.Int).Sub(curve.N, big.NewInt(2))
+	if d.Sign() <= 0 || d.Cmp(upper) > 0 {
+		return nil, errors.New("New_Private_Key error: d out of range")
+	}
+	x, y := curve.Scalar_Base_Mult(d)
+	return &Private_Key{Public_Key{curve, x, y}, new(big.Int).Set(d)}, nil
+}
+
+// 生成密钥对,私钥d在[1,n-2]上均匀选取。rand应为SM3 DRBG,长期密钥可使用(*drbg.Working_State).Critical()
+func Generate_Key(curve *Curve, rand io.Reader) (*Private_Key, error) {
+	d, err := random.New(rand).Big_Int_Range(big.NewInt(1), new(big.Int).Sub(curve.N, big.NewInt(2)))
+	if err != nil {
+		return nil, err
+	}
+	return New_Private_Key(curve, d)
+}
+
+// 大整数->定长字节串(大端序)
+func field_bytes(curve *Curve, n *big.Int) []byte {
+	return n.FillBytes(make([]byte, (curve.BitSize+7)/8))
+}
+
+// 用户杂凑值Z=SM3(ENTL||ID||a||b||Gx||Gy||Px||Py),ENTL为ID的比特长度(2字节)
+func (public_key *Public_Key) Z(id []byte) ([]byte, error) {
+	if len(id)*8 > 0xffff {
+		return nil, errors.New("Z error: ID too long")
+	}
+	curve := public_key.Curve
+	digest := sm3.New()
+	entl := len(id) * 8
+	digest.Write([]byte{byte(entl >> 8), byte(entl)})
+	digest.Write(id)
+	for _, n := range []*big.Int{curve.A, curve.B, curve.Gx, curve.Gy, public_key.X, public_key.Y} {
+		digest.Write(field_bytes(curve, n))
+	}
+	return digest.Sum(nil), nil
+}
+
+// 消息杂凑e=SM3(Z||M),作为整数
+func (public_key *Public_Key) digest(id []byte, message []byte) (*big.Int, error) {
+	Z, err := public_key.Z(id)
+	if err != nil {
+		return nil, err
+	}
+	digest := sm3.New()
+	digest.Write(Z)
+	digest.Write(message)
+	return new(big.Int).SetBytes(digest.Sum(nil)), nil
+}
+
+// 以给定的k签名:(x1,y1)=[k]G, r=(e+x1) mod n, s=((1+d)^-1·(k-r·d)) mod n。
+// 涉及k与d的运算均使用定时实现。r=0、r+k=n或s=0时返回ok=false,调用者须另取k
+func (private_key *Private_Key) sign_with_k(e *big.Int, k *big.Int) (*big.Int, *big.Int, bool) {
+	curve := private_key.Curve
+	x1, _ := curve.Scalar_Base_Mult(k)
+	r := new(big.Int).Add(e, x1)
+	r.Mod(r, curve.N)
+	if r.Sign() == 0 {
+		return nil, nil, false
+	}
+	fn := curve.fn
+	k_mont, r_mont, d_mont := fn.from_big(k), fn.from_big(r), fn.from_big(private_key.D)
+	if fn.is_zero(fn.add(r_mont, k_mont)) {
+		return nil, nil, false
+	}
+	s := fn.mul(fn.inverse(fn.add(d_mont, fn.one)), fn.sub(k_mont, fn.mul(r_mont, d_mont)))
+	if fn.is_zero(s) {
+		return nil, nil, false
+	}
+	return r, fn.to_big(s), true
+}
+
+// 签名,随机数k在[1,n-1]上均匀选取。rand应为SM3 DRBG(*drbg.Working_State),
+// 不宜对每个k使用Critical()进行单次检测,签名量大时误报会使DRBG进入错误状态
+func Sign(rand io.Reader, private_key *Private_Key, id []byte, message []byte) (*big.Int, *big.Int, error) {
+	e, err := private_key.digest(id, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	generator := random.New(rand)
+	upper := new(big.Int).Sub(private_key.Curve.N, big.NewInt(1))
+	for {
+		k, err := generator.Big_Int_Range(big.NewInt(1), upper)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r, s, ok := private_key.sign_with_k(e, k); ok {
+			return r, s, nil
+		}
+	}
+}
+
+// 验证签名:r,s∈[1,n-1], t=(r+s) mod n≠0, (x1,y1)=[s]G+[t]P, R=(e+x1) mod n, 检查R=r
+func Verify(public_key *Public_Key, id []byte, message []byte, r *big.Int, s *big.Int) bool {
+	curve := public_key.Curve
+	if r.Sign() <= 0 || r.Cmp(curve.N) >= 0 || s.Sign() <= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	if !curve.Is_On_Curve(public_key.X, public_key.Y) {
+		return false
+	}
+	e, err := public_key.digest(id, message)
+	if err != nil {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, curve.N)
+	if t.Sign() == 0 {
+		return false
+	}
+	//s、t与公钥均为公开值,使用变时运算
+	x1, y1 := curve.scalar_mult_vartime(curve.Gx, curve.Gy, s)
+	x2, y2 := curve.scalar_mult_vartime(public_key.X, public_key.Y, t)
+	x1, _ = curve.Add(x1, y1, x2, y2)
+	R := new(big.Int).Add(e, x1)
+	R.Mod(R, curve.N)
+	return R.Cmp(r) == 0
+}
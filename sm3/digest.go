@@ -0,0 +1,106 @@
+package sm3
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+const (
+	Size      = outlen //杂凑值的长度(单位:字节)
+	BlockSize = 64     //分组长度(单位:字节)
+
+	magic           = "sm3"                                //序列化状态的标识
+	marshal_version = 0x01                                 //序列化格式的版本号
+	marshaled_size  = len(magic) + 1 + 8*4 + BlockSize + 8 //序列化状态的长度(单位:字节)
+)
+
+// SM3杂凑计算器,实现hash.Hash与encoding.BinaryMarshaler/BinaryUnmarshaler
+type Digest struct {
+	working_state Working_State
+}
+
+// 创建SM3杂凑计算器
+func New() hash.Hash {
+	digest := new(Digest)
+	digest.Reset()
+	return digest
+}
+
+// 输入消息,可多次调用
+func (digest *Digest) Write(p []byte) (int, error) {
+	digest.working_state.Fill(p)
+	return len(p), nil
+}
+
+// 将当前杂凑值追加到b后返回,不改变内部状态,之后可继续输入
+func (digest *Digest) Sum(b []byte) []byte {
+	working_state := digest.working_state
+	working_state.Tail()
+	working_state.Hash()
+	var output [Size]byte
+	for i, v := range working_state.V {
+		binary.BigEndian.PutUint32(output[i*4:i*4+4], v)
+	}
+	return append(b, output[:]...)
+}
+
+// 重置为初始状态
+func (digest *Digest) Reset() {
+	digest.working_state.Init()
+}
+
+// 杂凑值的长度(单位:字节)
+func (digest *Digest) Size() int {
+	return Size
+}
+
+// 分组长度(单位:字节)
+func (digest *Digest) BlockSize() int {
+	return BlockSize
+}
+
+// 序列化内部状态:标识"sm3"||版本号||V||当前分组(不足部分补0)||已输入字节数
+func (digest *Digest) MarshalBinary() ([]byte, error) {
+	working_state := &digest.working_state
+	b := make([]byte, 0, marshaled_size)
+	b = append(b, magic...)
+	b = append(b, marshal_version)
+	for _, v := range working_state.V {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	var block [BlockSize]byte
+	for i := 0; i < working_state.W_PTR; i++ {
+		binary.BigEndian.PutUint32(block[i*4:i*4+4], working_state.W[i])
+	}
+	copy(block[working_state.W_PTR*4:], working_state.Buf[:working_state.Buf_PTR])
+	b = append(b, block[:]...)
+	b = binary.BigEndian.AppendUint64(b, uint64(working_state.Input_Length))
+	return b, nil
+}
+
+// 反序列化内部状态,可在其他进程或机器上继续输入
+func (digest *Digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic)+1 || string(b[:len(magic)]) != magic {
+		return errors.New("UnmarshalBinary error: invalid hash state identifier")
+	}
+	if b[len(magic)] != marshal_version {
+		return errors.New("UnmarshalBinary error: unsupported hash state version")
+	}
+	if len(b) != marshaled_size {
+		return errors.New("UnmarshalBinary error: invalid hash state size")
+	}
+	b = b[len(magic)+1:]
+	var V [8]uint32
+	for i := range V {
+		V[i] = binary.BigEndian.Uint32(b[i*4 : i*4+4])
+	}
+	b = b[8*4:]
+	block := b[:BlockSize]
+	length := binary.BigEndian.Uint64(b[BlockSize:])
+	digest.Reset()
+	digest.working_state.V = V
+	digest.working_state.Fill(block[:length%BlockSize])
+	digest.working_state.Input_Length = int(length)
+	return nil
+}
@@ -0,0 +1,20 @@
+package sm3
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// 创建以key为密钥的HMAC-SM3计算器
+func New_HMAC(key []byte) hash.Hash {
+	return hmac.New(New, key)
+}
+
+// HMAC-SM3,依次输入data中的各段消息
+func HMAC(key []byte, data ...[]byte) []byte {
+	mac := New_HMAC(key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
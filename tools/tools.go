@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"math/bits"
 )
 
@@ -71,3 +72,84 @@ func Get_Tj() {
 		}
 	}
 }
+
+// 正则化不完全伽马函数P(a,x)
+func Igam(a float64, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 0
+	}
+	if x > 1 && x > a {
+		return 1 - Igamc(a, x)
+	}
+	ax := a*math.Log(x) - x
+	lgamma_a, _ := math.Lgamma(a)
+	ax -= lgamma_a
+	if ax < -709.78271289338399 {
+		return 0
+	}
+	ax = math.Exp(ax)
+	r := a
+	c := 1.0
+	ans := 1.0
+	for c/ans > 1e-15 {
+		r++
+		c *= x / r
+		ans += c
+	}
+	return ans * ax / a
+}
+
+// 正则化不完全伽马函数Q(a,x)=1-P(a,x),用于卡方检验的P值计算
+func Igamc(a float64, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 1
+	}
+	if x < 1 || x < a {
+		return 1 - Igam(a, x)
+	}
+	ax := a*math.Log(x) - x
+	lgamma_a, _ := math.Lgamma(a)
+	ax -= lgamma_a
+	if ax < -709.78271289338399 {
+		return 0
+	}
+	ax = math.Exp(ax)
+	const big = 4.503599627370496e15
+	const big_inv = 2.22044604925031308085e-16
+	y := 1 - a
+	z := x + y + 1
+	c := 0.0
+	pkm2 := 1.0
+	qkm2 := x
+	pkm1 := x + 1
+	qkm1 := z * x
+	ans := pkm1 / qkm1
+	for {
+		c++
+		y++
+		z += 2
+		yc := y * c
+		pk := pkm1*z - pkm2*yc
+		qk := qkm1*z - qkm2*yc
+		t := 1.0
+		if qk != 0 {
+			r := pk / qk
+			t = math.Abs((ans - r) / r)
+			ans = r
+		}
+		pkm2 = pkm1
+		pkm1 = pk
+		qkm2 = qkm1
+		qkm1 = qk
+		if math.Abs(pk) > big {
+			pkm2 *= big_inv
+			pkm1 *= big_inv
+			qkm2 *= big_inv
+			qkm1 *= big_inv
+		}
+		if t <= 1e-15 {
+			break
+		}
+	}
+	return ans * ax
+}